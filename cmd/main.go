@@ -2,7 +2,12 @@ package main
 
 import (
 	"Todo/database"
+	"Todo/mailer"
+	"Todo/purger"
+	"Todo/rebalancer"
 	"Todo/server"
+	"Todo/storage"
+	"context"
 	"errors"
 	"github.com/sirupsen/logrus"
 	"net/http"
@@ -31,6 +36,46 @@ func main() {
 	}
 	logrus.Print("migration successful!!")
 
+	storageConfig, cfgErr := storage.ConfigFromEnv()
+	if cfgErr != nil {
+		logrus.Panicf("Failed to read storage configuration with error: %+v", cfgErr)
+	}
+	if err := storage.Setup(storageConfig); err != nil {
+		logrus.Panicf("Failed to set up blob storage with error: %+v", err)
+	}
+
+	mailConfig, cfgErr := mailer.ConfigFromEnv()
+	if cfgErr != nil {
+		logrus.Panicf("Failed to read mail configuration with error: %+v", cfgErr)
+	}
+	if err := mailer.Setup(mailConfig); err != nil {
+		logrus.Panicf("Failed to set up mailer with error: %+v", err)
+	}
+
+	purgeConfig, cfgErr := purger.ConfigFromEnv()
+	if cfgErr != nil {
+		logrus.Panicf("Failed to read purger configuration with error: %+v", cfgErr)
+	}
+
+	purgeCtx, stopPurger := context.WithCancel(context.Background())
+	purgerDone := make(chan struct{})
+	go func() {
+		defer close(purgerDone)
+		purger.New(purgeConfig, storage.Blobs).Run(purgeCtx)
+	}()
+
+	rebalanceConfig, cfgErr := rebalancer.ConfigFromEnv()
+	if cfgErr != nil {
+		logrus.Panicf("Failed to read rebalancer configuration with error: %+v", cfgErr)
+	}
+
+	rebalanceCtx, stopRebalancer := context.WithCancel(context.Background())
+	rebalancerDone := make(chan struct{})
+	go func() {
+		defer close(rebalancerDone)
+		rebalancer.New(rebalanceConfig).Run(rebalanceCtx)
+	}()
+
 	go func() {
 		if err := srv.Run(":8080"); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logrus.Panicf("Failed to run server with error: %+v", err)
@@ -42,6 +87,11 @@ func main() {
 
 	logrus.Info("shutting down server")
 
+	stopPurger()
+	stopRebalancer()
+	<-purgerDone
+	<-rebalancerDone
+
 	if err := database.ShutdownDatabase(); err != nil {
 		logrus.WithError(err).Error("failed to close database connection")
 	}
@@ -62,7 +62,7 @@ func migrateUp(db *sqlx.DB) error {
 	return nil
 }
 
-func Tx(fn func(tx *sqlx.Tx) error) error {
+func Tx(fn func(tx *sqlx.Tx) error) (err error) {
 	tx, err := Todo.Beginx()
 	if err != nil {
 		return fmt.Errorf("failed to start a transaction: %+v", err)
@@ -76,8 +76,26 @@ func Tx(fn func(tx *sqlx.Tx) error) error {
 		}
 		if commitErr := tx.Commit(); commitErr != nil {
 			logrus.Errorf("failed to commit tx: %s", commitErr)
+			err = commitErr
 		}
 	}()
 	err = fn(tx)
 	return err
 }
+
+// Savepoint runs fn inside a savepoint of tx. When fn fails only its own
+// statements are rolled back and tx stays usable, which Postgres otherwise
+// refuses after any error.
+func Savepoint(tx *sqlx.Tx, name string, fn func() error) error {
+	if _, err := tx.Exec("SAVEPOINT " + name); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if _, rollBackErr := tx.Exec("ROLLBACK TO SAVEPOINT " + name); rollBackErr != nil {
+			return fmt.Errorf("failed to rollback to savepoint: %w", rollBackErr)
+		}
+		return err
+	}
+	_, err := tx.Exec("RELEASE SAVEPOINT " + name)
+	return err
+}
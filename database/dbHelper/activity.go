@@ -0,0 +1,416 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"Todo/models"
+	"Todo/utils"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// RecordActivity adds an entry to the activity log. It runs in the
+// transaction of the change it records, so that both commit or neither does.
+func RecordActivity(db sqlx.Ext, activity models.Activity) error {
+	SQL := `INSERT INTO activity (actor_id, entity, entity_id, action, before, after)
+			  VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, crtErr := db.Exec(SQL, activity.ActorID, activity.Entity, activity.EntityID, activity.Action,
+		activity.Before, activity.After)
+	return crtErr
+}
+
+// RecordTodoActivity logs an action on a todo along with the todo before the
+// change, nil for new todos, and the todo as the transaction sees it now,
+// which is nil once it is purged.
+func RecordTodoActivity(db sqlx.Ext, actorID, todoID string, action models.ActivityAction, before *models.Todo) error {
+	after, err := TodoSnapshot(db, todoID)
+	if err != nil {
+		return err
+	}
+
+	activity := models.Activity{
+		ActorID:  actorID,
+		Entity:   models.ActivityEntityTodo,
+		EntityID: &todoID,
+		Action:   action,
+	}
+	if activity.Before, err = todoState(before); err != nil {
+		return err
+	}
+	if activity.After, err = todoState(after); err != nil {
+		return err
+	}
+	return RecordActivity(db, activity)
+}
+
+// RecordTodoChanges logs a change made to many todos at once, given the todos
+// as they were before it. Every todo gets an entry whose action follows from
+// what happened to it, and todos the change left alone get none.
+func RecordTodoChanges(db sqlx.Ext, actorID string, before []models.Todo) error {
+	if len(before) == 0 {
+		return nil
+	}
+
+	todoIDs := make([]string, 0, len(before))
+	for i := range before {
+		todoIDs = append(todoIDs, before[i].ID)
+	}
+
+	SQL := `SELECT ` + todoColumns + `
+			  FROM todos
+			  WHERE id = ANY($1)`
+
+	todos := make([]models.Todo, 0, len(before))
+	if getErr := sqlx.Select(db, &todos, SQL, pq.Array(todoIDs)); getErr != nil {
+		return getErr
+	}
+
+	after := make(map[string]*models.Todo, len(todos))
+	for i := range todos {
+		after[todos[i].ID] = &todos[i]
+	}
+
+	activities, err := todoChanges(actorID, before, after)
+	if err != nil {
+		return err
+	}
+	for _, activity := range activities {
+		if err = RecordActivity(db, activity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordChange logs a change to an entity other than a todo. before and after
+// are encoded to JSON as they are, and nil leaves them out.
+func RecordChange(db sqlx.Ext, actorID string, entity models.ActivityEntity, entityID string,
+	action models.ActivityAction, before, after interface{}) error {
+	activity, err := changeActivity(actorID, entity, entityID, action, before, after)
+	if err != nil {
+		return err
+	}
+	return RecordActivity(db, activity)
+}
+
+// changeActivity builds the activity log entry of RecordChange.
+func changeActivity(actorID string, entity models.ActivityEntity, entityID string,
+	action models.ActivityAction, before, after interface{}) (models.Activity, error) {
+	activity := models.Activity{
+		ActorID:  actorID,
+		Entity:   entity,
+		EntityID: &entityID,
+		Action:   action,
+	}
+
+	var err error
+	if activity.Before, err = activityState(before); err != nil {
+		return activity, err
+	}
+	activity.After, err = activityState(after)
+	return activity, err
+}
+
+// TodoSnapshot returns a todo as the transaction sees it, whoever owns it and
+// whether or not it is archived, or nil when there is no such todo. It is
+// meant for the activity log and does no access checks.
+func TodoSnapshot(db sqlx.Ext, todoID string) (*models.Todo, error) {
+	SQL := `SELECT ` + todoColumns + `
+			  FROM todos
+			  WHERE id = $1`
+
+	if err := validateUUIDs(todoID); err != nil {
+		return nil, err
+	}
+
+	var todo models.Todo
+	getErr := sqlx.Get(db, &todo, SQL, todoID)
+	if errors.Is(getErr, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if getErr != nil {
+		return nil, getErr
+	}
+	return &todo, nil
+}
+
+// OwnedTodoSnapshots returns the todos a user owns that are not archived, for
+// the activity log of changes made to all of them at once.
+func OwnedTodoSnapshots(db sqlx.Ext, userID string) ([]models.Todo, error) {
+	SQL := `SELECT ` + todoColumns + `
+			  FROM todos
+			  WHERE user_id = $1
+			    AND archived_at IS NULL`
+
+	todos := make([]models.Todo, 0)
+	getErr := sqlx.Select(db, &todos, SQL, userID)
+	return todos, getErr
+}
+
+// TodoTreeSnapshots returns a todo and all of its descendants, archived or
+// not, for the activity log of changes that carry over to subtasks.
+func TodoTreeSnapshots(db sqlx.Ext, todoID string) ([]models.Todo, error) {
+	SQL := `WITH RECURSIVE tree AS (
+				SELECT id
+				  FROM todos
+				  WHERE id = $1
+				UNION ALL
+				SELECT t.id
+				  FROM todos t
+				  JOIN tree ON t.parent_id = tree.id
+			)
+			SELECT ` + todoColumns + `
+			  FROM todos
+			  WHERE id IN (SELECT id FROM tree)`
+
+	if err := validateUUIDs(todoID); err != nil {
+		return nil, err
+	}
+
+	todos := make([]models.Todo, 0)
+	getErr := sqlx.Select(db, &todos, SQL, todoID)
+	return todos, getErr
+}
+
+// ProjectTodoSnapshots returns every todo of a project, archived or not, for
+// the activity log of changes to the project that carry over to its todos.
+func ProjectTodoSnapshots(db sqlx.Ext, projectID string) ([]models.Todo, error) {
+	SQL := `SELECT ` + todoColumns + `
+			  FROM todos
+			  WHERE project_id = $1`
+
+	if err := validateUUIDs(projectID); err != nil {
+		return nil, err
+	}
+
+	todos := make([]models.Todo, 0)
+	getErr := sqlx.Select(db, &todos, SQL, projectID)
+	return todos, getErr
+}
+
+// WorkspaceTodoSnapshots returns every todo in the projects of a workspace
+// for the activity log of deleting the workspace.
+func WorkspaceTodoSnapshots(db sqlx.Ext, workspaceID string) ([]models.Todo, error) {
+	SQL := `SELECT ` + todoColumns + `
+			  FROM todos
+			  WHERE project_id IN (SELECT id
+			                         FROM projects
+			                         WHERE workspace_id = $1)`
+
+	if err := validateUUIDs(workspaceID); err != nil {
+		return nil, err
+	}
+
+	todos := make([]models.Todo, 0)
+	getErr := sqlx.Select(db, &todos, SQL, workspaceID)
+	return todos, getErr
+}
+
+// StatusTodoSnapshots returns every todo in a status, archived or not, for
+// the activity log of changes to the status that carry over to its todos.
+func StatusTodoSnapshots(db sqlx.Ext, statusID string) ([]models.Todo, error) {
+	SQL := `SELECT ` + todoColumns + `
+			  FROM todos
+			  WHERE status_id = $1`
+
+	if err := validateUUIDs(statusID); err != nil {
+		return nil, err
+	}
+
+	todos := make([]models.Todo, 0)
+	getErr := sqlx.Select(db, &todos, SQL, statusID)
+	return todos, getErr
+}
+
+// activityPageFilter narrows down and pages a set of activity rows aliased a.
+// It is applied to each source of GetActivity on its own so that every source
+// reads no more than a page off its index.
+const activityPageFilter = `
+			    AND ($2 = '' OR (a.entity = 'todo' AND a.entity_id = CAST(NULLIF($2, '') AS UUID)))
+			    AND ($3 = '' OR a.entity = $3)
+			    AND ($4 = '' OR a.action = $4)
+			    AND (CAST($5 AS TIMESTAMPTZ) IS NULL OR (a.created_at, a.id) < (CAST($5 AS TIMESTAMPTZ), CAST($6 AS UUID)))
+			  ORDER BY a.created_at DESC, a.id DESC
+			  LIMIT $7`
+
+// GetActivity lists the activity log as far as a user may see it: their own
+// actions and the history of the todos they have access to, newest first.
+// The todos are worked out once up front, as owned, shared directly or
+// through a parent, or part of a shared or workspace project, which is what
+// todo_role grants access by.
+func GetActivity(userID string, filters models.ActivityFilters) (models.ActivityPage, error) {
+	page := models.ActivityPage{Items: make([]models.Activity, 0)}
+
+	var cursorAt, cursorID interface{}
+	if filters.Cursor != nil {
+		cursorAt, cursorID = filters.Cursor.CreatedAt, filters.Cursor.ID
+	}
+
+	SQL := `WITH RECURSIVE projects_in_reach AS (
+				SELECT project_id
+				  FROM shares
+				  WHERE user_id = $1
+				    AND project_id IS NOT NULL
+				    AND archived_at IS NULL
+				UNION
+				SELECT p.id
+				  FROM projects p
+				  JOIN workspace_members m ON m.workspace_id = p.workspace_id
+				  JOIN workspaces w ON w.id = m.workspace_id
+				  WHERE m.user_id = $1
+				    AND m.archived_at IS NULL
+				    AND w.archived_at IS NULL
+			), todos_in_reach AS (
+				SELECT id
+				  FROM todos
+				  WHERE user_id = $1
+				UNION
+				SELECT id
+				  FROM todos
+				  WHERE project_id IN (SELECT project_id FROM projects_in_reach)
+				UNION
+				SELECT todo_id
+				  FROM shares
+				  WHERE user_id = $1
+				    AND todo_id IS NOT NULL
+				    AND archived_at IS NULL
+				UNION
+				SELECT t.id
+				  FROM todos t
+				  JOIN todos_in_reach r ON t.parent_id = r.id
+			), visible AS (
+				(SELECT a.*
+				   FROM activity a
+				   WHERE a.actor_id = $1` + activityPageFilter + `)
+				UNION
+				(SELECT a.*
+				   FROM activity a
+				   WHERE a.entity = 'todo'
+				     AND a.entity_id IN (SELECT id FROM todos_in_reach)` + activityPageFilter + `)
+			)
+			SELECT a.id, a.actor_id, u.name AS actor_name, a.entity, a.entity_id, a.action,
+			       a.before, a.after, a.created_at
+			  FROM visible a
+			  JOIN users u ON u.id = a.actor_id
+			  ORDER BY a.created_at DESC, a.id DESC
+			  LIMIT $7`
+
+	// one extra row is fetched to find out whether another page follows
+	getErr := database.Todo.Select(&page.Items, SQL, userID, filters.TodoID, filters.Entity, filters.Action,
+		cursorAt, cursorID, filters.Limit+1)
+	if getErr != nil {
+		return page, getErr
+	}
+
+	if len(page.Items) > filters.Limit {
+		page.Items = page.Items[:filters.Limit]
+		last := page.Items[len(page.Items)-1]
+		cursor, encErr := utils.EncodeCursor(models.ActivityCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		if encErr != nil {
+			return page, encErr
+		}
+		page.NextCursor = cursor
+	}
+	return page, nil
+}
+
+// todoChanges builds the activity log entries of a change to many todos from
+// the todos before and after it. A todo missing from after was purged.
+func todoChanges(actorID string, before []models.Todo, after map[string]*models.Todo) ([]models.Activity, error) {
+	activities := make([]models.Activity, 0, len(before))
+	for i := range before {
+		previous, current := &before[i], after[before[i].ID]
+
+		touched, err := todoTouched(previous, current)
+		if err != nil {
+			return nil, err
+		}
+		if !touched {
+			continue
+		}
+
+		activity := models.Activity{
+			ActorID:  actorID,
+			Entity:   models.ActivityEntityTodo,
+			EntityID: &previous.ID,
+			Action:   TodoChange(*previous, current),
+		}
+		if activity.Before, err = todoState(previous); err != nil {
+			return nil, err
+		}
+		if activity.After, err = todoState(current); err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+	return activities, nil
+}
+
+// TodoChange names the action of the activity log that turned previous into
+// todo, which is nil once the todo is purged.
+func TodoChange(previous models.Todo, todo *models.Todo) models.ActivityAction {
+	switch {
+	case todo == nil:
+		return models.ActivityPurge
+	case previous.ArchivedAt == nil && todo.ArchivedAt != nil:
+		return models.ActivityDelete
+	case previous.ArchivedAt != nil && todo.ArchivedAt == nil:
+		return models.ActivityRestore
+	case !previous.IsCompleted && todo.IsCompleted:
+		return models.ActivityComplete
+	case previous.IsCompleted && !todo.IsCompleted:
+		return models.ActivityReopen
+	default:
+		return models.ActivityUpdate
+	}
+}
+
+// todoTouched tells whether a change altered a todo itself. The names and
+// counts looked up from statuses, users, subtasks and comments are left out,
+// since changes to those are logged where they happen.
+func todoTouched(previous, todo *models.Todo) (bool, error) {
+	if todo == nil {
+		return true, nil
+	}
+
+	states := make([][]byte, 0, 2)
+	for _, state := range []models.Todo{*previous, *todo} {
+		state.AssigneeName, state.StatusName, state.StatusCategory = nil, "", ""
+		state.SubtasksDone, state.SubtasksTotal, state.CommentsCount = 0, 0, 0
+
+		data, err := json.Marshal(state)
+		if err != nil {
+			return false, err
+		}
+		states = append(states, data)
+	}
+	return !bytes.Equal(states[0], states[1]), nil
+}
+
+// activityState encodes any entity for the before and after columns of the
+// activity log.
+func activityState(v interface{}) (*json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	state := json.RawMessage(data)
+	return &state, nil
+}
+
+// todoState encodes a todo for the before and after columns of the activity
+// log, keeping a missing todo as NULL.
+func todoState(todo *models.Todo) (*json.RawMessage, error) {
+	if todo == nil {
+		return nil, nil
+	}
+	return activityState(todo)
+}
@@ -0,0 +1,175 @@
+package dbHelper
+
+import (
+	"Todo/models"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTodoChanges(t *testing.T) {
+	now := time.Now()
+	parentID := "parent"
+
+	open := models.Todo{ID: "open", Name: "groceries", ParentID: &parentID, StatusName: "To do"}
+	done := models.Todo{ID: "done", Name: "laundry", ParentID: &parentID, IsCompleted: true, CompletedAt: &now}
+	trashed := models.Todo{ID: "trashed", Name: "dishes", ArchivedAt: &now}
+
+	completed := open
+	completed.IsCompleted, completed.CompletedAt = true, &now
+	completed.StatusName, completed.StatusCategory = "Done", models.StatusCategoryDone
+
+	reopened := done
+	reopened.IsCompleted, reopened.CompletedAt = false, nil
+
+	deleted := open
+	deleted.ArchivedAt = &now
+
+	restored := trashed
+	restored.ArchivedAt = nil
+
+	renamed := open
+	renamed.Name = "shopping"
+
+	recounted := open
+	recounted.SubtasksTotal, recounted.CommentsCount = 3, 1
+	recounted.StatusName = "Backlog"
+
+	tests := []struct {
+		name   string
+		before models.Todo
+		after  *models.Todo
+		want   models.ActivityAction
+	}{
+		{name: "completed", before: open, after: &completed, want: models.ActivityComplete},
+		{name: "reopened", before: done, after: &reopened, want: models.ActivityReopen},
+		{name: "deleted", before: open, after: &deleted, want: models.ActivityDelete},
+		{name: "restored", before: trashed, after: &restored, want: models.ActivityRestore},
+		{name: "purged", before: trashed, after: nil, want: models.ActivityPurge},
+		{name: "renamed", before: open, after: &renamed, want: models.ActivityUpdate},
+		{name: "unchanged", before: done, after: &done},
+		{name: "derived fields only", before: open, after: &recounted},
+	}
+
+	for _, tt := range tests {
+		after := map[string]*models.Todo{}
+		if tt.after != nil {
+			after[tt.before.ID] = tt.after
+		}
+
+		activities, err := todoChanges("actor", []models.Todo{tt.before}, after)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if tt.want == "" {
+			if len(activities) != 0 {
+				t.Errorf("%s: got %d entries, want none", tt.name, len(activities))
+			}
+			continue
+		}
+		if len(activities) != 1 {
+			t.Errorf("%s: got %d entries, want 1", tt.name, len(activities))
+			continue
+		}
+
+		activity := activities[0]
+		if activity.Action != tt.want {
+			t.Errorf("%s: got action %q, want %q", tt.name, activity.Action, tt.want)
+		}
+		if activity.ActorID != "actor" || activity.Entity != models.ActivityEntityTodo ||
+			activity.EntityID == nil || *activity.EntityID != tt.before.ID {
+			t.Errorf("%s: got actor %q and entity %q %v", tt.name, activity.ActorID, activity.Entity, activity.EntityID)
+		}
+		if activity.Before == nil {
+			t.Errorf("%s: missing before state", tt.name)
+		}
+		if (activity.After == nil) != (tt.after == nil) {
+			t.Errorf("%s: got after state %v", tt.name, activity.After)
+		}
+	}
+}
+
+// TestTodoChangesCascade checks that completing a todo with its subtasks logs
+// the todo and each subtask it completed, but not subtasks done before.
+func TestTodoChangesCascade(t *testing.T) {
+	now := time.Now()
+	rootID := "root"
+
+	before := []models.Todo{
+		{ID: rootID, Name: "move house", SubtasksTotal: 2, SubtasksDone: 1},
+		{ID: "open", Name: "pack", ParentID: &rootID},
+		{ID: "done", Name: "book van", ParentID: &rootID, IsCompleted: true, CompletedAt: &now},
+	}
+
+	after := make(map[string]*models.Todo, len(before))
+	for _, todo := range before {
+		todo := todo
+		if !todo.IsCompleted {
+			todo.IsCompleted, todo.CompletedAt = true, &now
+		}
+		if todo.ID == rootID {
+			todo.SubtasksDone = 2
+		}
+		after[todo.ID] = &todo
+	}
+
+	activities, err := todoChanges("actor", before, after)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	logged := make(map[string]models.ActivityAction, len(activities))
+	for _, activity := range activities {
+		logged[*activity.EntityID] = activity.Action
+	}
+	want := map[string]models.ActivityAction{rootID: models.ActivityComplete, "open": models.ActivityComplete}
+	if len(logged) != len(want) {
+		t.Fatalf("got entries %v, want %v", logged, want)
+	}
+	for id, action := range want {
+		if logged[id] != action {
+			t.Errorf("todo %s: got action %q, want %q", id, logged[id], action)
+		}
+	}
+}
+
+func TestChangeActivity(t *testing.T) {
+	tag := models.Tag{ID: "tag", Name: "errands"}
+	renamed := models.Tag{ID: "tag", Name: "chores"}
+
+	tests := []struct {
+		name   string
+		action models.ActivityAction
+		before interface{}
+		after  interface{}
+		want   [2]string
+	}{
+		{name: "create", action: models.ActivityCreate, after: tag, want: [2]string{"", `{"id":"tag","name":"errands"}`}},
+		{name: "delete", action: models.ActivityDelete, before: tag, want: [2]string{`{"id":"tag","name":"errands"}`, ""}},
+		{name: "update", action: models.ActivityUpdate, before: tag, after: renamed,
+			want: [2]string{`{"id":"tag","name":"errands"}`, `{"id":"tag","name":"chores"}`}},
+	}
+
+	for _, tt := range tests {
+		activity, err := changeActivity("actor", models.ActivityEntityTag, "tag", tt.action, tt.before, tt.after)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if activity.ActorID != "actor" || activity.Entity != models.ActivityEntityTag ||
+			activity.EntityID == nil || *activity.EntityID != "tag" || activity.Action != tt.action {
+			t.Errorf("%s: got %+v", tt.name, activity)
+		}
+
+		for i, state := range []*json.RawMessage{activity.Before, activity.After} {
+			got := ""
+			if state != nil {
+				got = string(*state)
+			}
+			if got != tt.want[i] {
+				t.Errorf("%s: got state %q, want %q", tt.name, got, tt.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"Todo/models"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+)
+
+const attachmentColumns = `id, todo_id, file_name, content_type, size, storage_key, created_at`
+
+// CreateAttachment records a file uploaded by userID on a live todo they may
+// change. The blob is stored under the returned StorageKey afterwards, in the
+// same transaction so that a failed upload leaves no row behind.
+func CreateAttachment(db sqlx.Ext, todoID, userID string, attachment models.Attachment) (models.Attachment, error) {
+	SQL := `INSERT INTO todo_attachments (id, todo_id, user_id, file_name, content_type, size, storage_key)
+			  SELECT new.id, todos.id, CAST($2 AS UUID), $3, $4, $5, CAST(todos.id AS TEXT) || '/' || new.id
+			    FROM todos,
+			         (SELECT gen_random_uuid() AS id) new
+			    WHERE todos.id = $1
+			      AND todo_role(todos.id, $2) IN ('owner', 'editor')
+			      AND todos.archived_at IS NULL
+			  RETURNING ` + attachmentColumns
+
+	if err := validateUUIDs(todoID); err != nil {
+		return attachment, err
+	}
+
+	crtErr := sqlx.Get(db, &attachment, SQL, todoID, userID,
+		attachment.FileName, attachment.ContentType, attachment.Size)
+	return attachment, todoMissing(db, todoID, userID, crtErr)
+}
+
+// GetAttachments lists the live attachments of a todo, oldest first.
+func GetAttachments(todoID, userID string) ([]models.Attachment, error) {
+	if _, err := GetTodo(todoID, userID); err != nil {
+		return nil, err
+	}
+
+	SQL := `SELECT ` + attachmentColumns + `
+			  FROM todo_attachments
+			  WHERE todo_id = $1
+			    AND archived_at IS NULL
+			  ORDER BY created_at, id`
+
+	attachments := make([]models.Attachment, 0)
+	getErr := database.Todo.Select(&attachments, SQL, todoID)
+	return attachments, getErr
+}
+
+func GetAttachment(attachmentID, todoID, userID string) (models.Attachment, error) {
+	SQL := `SELECT ` + attachmentColumns + `
+			  FROM todo_attachments a
+			  WHERE id = $1
+			    AND todo_id = $2
+			    AND archived_at IS NULL
+			    AND EXISTS (SELECT 1
+			                  FROM todos
+			                  WHERE id = a.todo_id
+			                    AND todo_role(id, $3) IS NOT NULL
+			                    AND archived_at IS NULL)`
+
+	var attachment models.Attachment
+	if err := validateUUIDs(attachmentID, todoID); err != nil {
+		return attachment, err
+	}
+
+	getErr := database.Todo.Get(&attachment, SQL, attachmentID, todoID, userID)
+	if errors.Is(getErr, sql.ErrNoRows) {
+		return attachment, missingOnTodo(database.Todo, todoID, userID, ErrAttachmentNotFound)
+	}
+	return attachment, getErr
+}
+
+// DeleteAttachment archives an attachment and returns it. The blob stays in
+// storage until the purger removes archived attachments.
+func DeleteAttachment(db sqlx.Ext, attachmentID, todoID, userID string) (models.Attachment, error) {
+	SQL := `UPDATE todo_attachments a
+			  SET archived_at = NOW()
+			  WHERE id = $1
+			    AND todo_id = $2
+			    AND archived_at IS NULL
+			    AND EXISTS (SELECT 1
+			                  FROM todos
+			                  WHERE id = a.todo_id
+			                    AND todo_role(id, $3) IN ('owner', 'editor')
+			                    AND archived_at IS NULL)
+			  RETURNING ` + attachmentColumns
+
+	var attachment models.Attachment
+	if err := validateUUIDs(attachmentID, todoID); err != nil {
+		return attachment, err
+	}
+
+	delErr := sqlx.Get(db, &attachment, SQL, attachmentID, todoID, userID)
+	if errors.Is(delErr, sql.ErrNoRows) {
+		return attachment, missingOnTodo(db, todoID, userID, ErrAttachmentNotFound)
+	}
+	return attachment, delErr
+}
@@ -0,0 +1,75 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"Todo/models"
+	"github.com/lib/pq"
+)
+
+// GetBoard returns the board of the workflow used by a project, or of the
+// default workflow when projectID is empty. A board of the default workflow
+// holds the todos of every project without a workflow of its own unless
+// projectID narrows it down. Each column lists at most limit todos in manual
+// order.
+func GetBoard(userID, projectID string, limit int) (models.Board, error) {
+	board := models.Board{Columns: make([]models.BoardColumn, 0)}
+	if projectID != "" {
+		board.ProjectID = &projectID
+	}
+
+	SQL := `SELECT ` + statusColumns + `,
+				(SELECT count(*)
+				   FROM todos
+				   WHERE status_id = statuses.id
+				     AND ($2 = '' OR project_id = CAST($2 AS UUID))
+				     AND archived_at IS NULL) AS count,
+				wip_limit IS NOT NULL AND (SELECT count(*)
+				                             FROM todos
+				                             WHERE status_id = statuses.id
+				                               AND archived_at IS NULL) >= wip_limit AS at_limit
+			  FROM statuses
+			  WHERE user_id = $1
+			    AND project_id IS NOT DISTINCT FROM workflow_project($1, CAST(NULLIF($2, '') AS UUID))
+			    AND archived_at IS NULL
+			  ORDER BY position`
+
+	if getErr := database.Todo.Select(&board.Columns, SQL, userID, projectID); getErr != nil {
+		return board, getErr
+	}
+
+	statusIDs := make([]string, len(board.Columns))
+	for i := range board.Columns {
+		statusIDs[i] = board.Columns[i].ID
+		board.Columns[i].Todos = make([]models.Todo, 0)
+	}
+
+	SQL = `SELECT ` + todoColumns + `
+			 FROM (SELECT *,
+			              row_number() OVER (PARTITION BY status_id ORDER BY position, id) AS column_index
+			         FROM todos
+			         WHERE user_id = $1
+			           AND status_id = ANY($2)
+			           AND ($3 = '' OR project_id = CAST($3 AS UUID))
+			           AND archived_at IS NULL) todos
+			 WHERE column_index <= $4
+			 ORDER BY position, id`
+
+	todos := make([]models.Todo, 0)
+	if getErr := database.Todo.Select(&todos, SQL, userID, pq.Array(statusIDs), projectID, limit); getErr != nil {
+		return board, getErr
+	}
+	if loadErr := loadTodoRelations(todos, userID); loadErr != nil {
+		return board, loadErr
+	}
+
+	columns := make(map[string]*models.BoardColumn, len(board.Columns))
+	for i := range board.Columns {
+		columns[board.Columns[i].ID] = &board.Columns[i]
+	}
+	for _, todo := range todos {
+		if column, ok := columns[todo.StatusID]; ok {
+			column.Todos = append(column.Todos, todo)
+		}
+	}
+	return board, nil
+}
@@ -0,0 +1,133 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"Todo/models"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+)
+
+const commentColumns = `id, todo_id, user_id, body, created_at, edited_at,
+			(SELECT name FROM users WHERE id = todo_comments.user_id) AS author_name`
+
+// CreateComment adds a comment by userID to a live todo they may change.
+func CreateComment(db sqlx.Ext, todoID, userID, body string) (models.Comment, error) {
+	SQL := `INSERT INTO todo_comments (todo_id, user_id, body)
+			  SELECT id, CAST($2 AS UUID), $3
+			    FROM todos
+			    WHERE id = $1
+			      AND todo_role(id, $2) IN ('owner', 'editor')
+			      AND archived_at IS NULL
+			  RETURNING ` + commentColumns
+
+	var comment models.Comment
+	if err := validateUUIDs(todoID); err != nil {
+		return comment, err
+	}
+
+	crtErr := sqlx.Get(db, &comment, SQL, todoID, userID, body)
+	return comment, todoMissing(db, todoID, userID, crtErr)
+}
+
+// GetComments lists the live comments of a todo, oldest first.
+func GetComments(todoID, userID string) ([]models.Comment, error) {
+	if _, err := GetTodo(todoID, userID); err != nil {
+		return nil, err
+	}
+
+	SQL := `SELECT ` + commentColumns + `
+			  FROM todo_comments
+			  WHERE todo_id = $1
+			    AND archived_at IS NULL
+			  ORDER BY created_at, id`
+
+	comments := make([]models.Comment, 0)
+	getErr := database.Todo.Select(&comments, SQL, todoID)
+	return comments, getErr
+}
+
+// LockComment returns a live comment of userID on a todo they can see and
+// locks it until the end of the transaction, so that a change can be logged
+// with the comment before it.
+func LockComment(db sqlx.Ext, commentID, todoID, userID string) (models.Comment, error) {
+	SQL := `SELECT ` + commentColumns + `
+			  FROM todo_comments
+			  WHERE id = $1
+			    AND todo_id = $2
+			    AND user_id = $3
+			    AND archived_at IS NULL
+			    AND EXISTS (SELECT 1
+			                  FROM todos
+			                  WHERE id = $2
+			                    AND todo_role(id, $3) IS NOT NULL
+			                    AND archived_at IS NULL)
+			  FOR UPDATE`
+
+	var comment models.Comment
+	if err := validateUUIDs(commentID, todoID); err != nil {
+		return comment, err
+	}
+
+	getErr := sqlx.Get(db, &comment, SQL, commentID, todoID, userID)
+	if errors.Is(getErr, sql.ErrNoRows) {
+		return comment, missingOnTodo(db, todoID, userID, ErrCommentNotFound)
+	}
+	return comment, getErr
+}
+
+// UpdateComment replaces the body of a comment. Only its author may edit it.
+func UpdateComment(db sqlx.Ext, commentID, todoID, userID, body string) (models.Comment, error) {
+	SQL := `UPDATE todo_comments
+			  SET body = $4,
+			      edited_at = NOW()
+			  WHERE id = $1
+			    AND todo_id = $2
+			    AND user_id = $3
+			    AND archived_at IS NULL
+			    AND EXISTS (SELECT 1
+			                  FROM todos
+			                  WHERE id = $2
+			                    AND todo_role(id, $3) IS NOT NULL
+			                    AND archived_at IS NULL)
+			  RETURNING ` + commentColumns
+
+	var comment models.Comment
+	if err := validateUUIDs(commentID, todoID); err != nil {
+		return comment, err
+	}
+
+	updErr := sqlx.Get(db, &comment, SQL, commentID, todoID, userID, body)
+	if errors.Is(updErr, sql.ErrNoRows) {
+		return comment, missingOnTodo(db, todoID, userID, ErrCommentNotFound)
+	}
+	return comment, updErr
+}
+
+// DeleteComment archives a comment and returns it. Only its author may
+// delete it.
+func DeleteComment(db sqlx.Ext, commentID, todoID, userID string) (models.Comment, error) {
+	SQL := `UPDATE todo_comments
+			  SET archived_at = NOW()
+			  WHERE id = $1
+			    AND todo_id = $2
+			    AND user_id = $3
+			    AND archived_at IS NULL
+			    AND EXISTS (SELECT 1
+			                  FROM todos
+			                  WHERE id = $2
+			                    AND todo_role(id, $3) IS NOT NULL
+			                    AND archived_at IS NULL)
+			  RETURNING ` + commentColumns
+
+	var comment models.Comment
+	if err := validateUUIDs(commentID, todoID); err != nil {
+		return comment, err
+	}
+
+	delErr := sqlx.Get(db, &comment, SQL, commentID, todoID, userID)
+	if errors.Is(delErr, sql.ErrNoRows) {
+		return comment, missingOnTodo(db, todoID, userID, ErrCommentNotFound)
+	}
+	return comment, delErr
+}
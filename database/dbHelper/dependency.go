@@ -0,0 +1,191 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"Todo/models"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// AddTodoBlockers marks a todo the user may change as blocked by each of
+// blockerIDs, all of which have to be live todos of the same owner that the
+// user can see. Edges that would close a cycle are rejected, and the
+// dependency changes of an owner's todos are serialized so that two
+// concurrent requests cannot close one between them. It returns the IDs of
+// the blockers the todo did not have yet.
+func AddTodoBlockers(db sqlx.Ext, todoID, userID string, blockerIDs []string) ([]string, error) {
+	if err := validateUUIDs(append([]string{todoID}, blockerIDs...)...); err != nil {
+		return nil, err
+	}
+
+	SQL := `SELECT user_id
+			 FROM todos
+			 WHERE id = $1
+			   AND todo_role(id, $2) IN ('owner', 'editor')
+			   AND archived_at IS NULL`
+
+	var ownerID string
+	if chkErr := sqlx.Get(db, &ownerID, SQL, todoID, userID); chkErr != nil {
+		return nil, todoMissing(db, todoID, userID, chkErr)
+	}
+
+	if lockErr := lockUser(db, "todo_dependencies", ownerID); lockErr != nil {
+		return nil, lockErr
+	}
+
+	SQL = `SELECT count(DISTINCT id)
+			 FROM todos
+			 WHERE id = ANY($1)
+			   AND user_id = $2
+			   AND todo_role(id, $3) IS NOT NULL
+			   AND archived_at IS NULL`
+
+	var owned int
+	if chkErr := sqlx.Get(db, &owned, SQL, pq.Array(blockerIDs), ownerID, userID); chkErr != nil {
+		return nil, chkErr
+	}
+	if owned != countDistinct(blockerIDs) {
+		return nil, ErrBlockerNotFound
+	}
+
+	// archived todos are followed as well, so restoring them cannot bring
+	// back a cycle either
+	SQL = `WITH RECURSIVE upstream AS (
+				SELECT unnest(CAST($1 AS UUID[])) AS id
+				UNION
+				SELECT d.blocker_id
+				  FROM todo_dependencies d
+				  JOIN upstream u ON d.todo_id = u.id
+			)
+			SELECT count(*) > 0
+			  FROM upstream
+			  WHERE id = $2`
+
+	var cycle bool
+	if chkErr := sqlx.Get(db, &cycle, SQL, pq.Array(blockerIDs), todoID); chkErr != nil {
+		return nil, chkErr
+	}
+	if cycle {
+		return nil, ErrDependencyCycle
+	}
+
+	SQL = `INSERT INTO todo_dependencies (todo_id, blocker_id)
+			 SELECT $1, unnest(CAST($2 AS UUID[]))
+			 ON CONFLICT DO NOTHING
+			 RETURNING blocker_id`
+
+	added := make([]string, 0, len(blockerIDs))
+	crtErr := sqlx.Select(db, &added, SQL, todoID, pq.Array(blockerIDs))
+	return added, crtErr
+}
+
+// RemoveTodoBlocker removes a blocker from a live todo the user may change.
+func RemoveTodoBlocker(db sqlx.Ext, todoID, blockerID, userID string) error {
+	SQL := `DELETE FROM todo_dependencies d
+			  USING todos td
+			  WHERE d.todo_id = td.id
+			    AND d.todo_id = $1
+			    AND d.blocker_id = $2
+			    AND todo_role(td.id, $3) IN ('owner', 'editor')
+			    AND td.archived_at IS NULL`
+
+	if err := validateUUIDs(todoID, blockerID); err != nil {
+		return err
+	}
+
+	delErr := expectAffected(db.Exec(SQL, todoID, blockerID, userID))
+	if errors.Is(delErr, ErrNotFound) {
+		return missingOnTodo(db, todoID, userID, ErrNotFound)
+	}
+	return delErr
+}
+
+// CountOpenBlockers counts the open todos blocking a todo or, with cascade,
+// any of its subtasks. Blockers inside that subtree are left out since they
+// get completed along with it.
+func CountOpenBlockers(db sqlx.Ext, todoID, userID string, cascade bool) (int, error) {
+	SQL := `WITH RECURSIVE tree AS (
+				SELECT id
+				  FROM todos
+				  WHERE id = $1
+				    AND todo_role(id, $2) IN ('owner', 'editor')
+				    AND archived_at IS NULL
+				UNION ALL
+				SELECT t.id
+				  FROM todos t
+				  JOIN tree ON t.parent_id = tree.id
+				  WHERE $3
+				    AND t.archived_at IS NULL
+			)
+			SELECT count(DISTINCT b.id)
+			  FROM todo_dependencies d
+			  JOIN todos b ON b.id = d.blocker_id
+			  WHERE d.todo_id IN (SELECT id FROM tree)
+			    AND b.id NOT IN (SELECT id FROM tree)
+			    AND NOT b.is_completed
+			    AND b.archived_at IS NULL`
+
+	if err := validateUUIDs(todoID); err != nil {
+		return 0, err
+	}
+
+	var open int
+	getErr := sqlx.Get(db, &open, SQL, todoID, userID, cascade)
+	return open, getErr
+}
+
+// loadTodoDependencies fills in both ends of the dependencies of every todo
+// with a single query. Archived todos on the other end are left out, and so
+// are todos the user cannot see, so that their names do not leak through a
+// todo shared with them.
+func loadTodoDependencies(todos []models.Todo, userID string) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	todoIDs := make([]string, 0, len(todos))
+	for i := range todos {
+		todoIDs = append(todoIDs, todos[i].ID)
+		todos[i].BlockedBy = make([]models.TodoLink, 0)
+		todos[i].Blocks = make([]models.TodoLink, 0)
+	}
+
+	SQL := `SELECT d.todo_id, true AS blocked_by, b.id, b.name, b.is_completed
+			  FROM todo_dependencies d
+			  JOIN todos b ON b.id = d.blocker_id
+			  WHERE d.todo_id = ANY($1)
+			    AND b.archived_at IS NULL
+			    AND todo_role(b.id, $2) IS NOT NULL
+			UNION ALL
+			SELECT d.blocker_id, false, t.id, t.name, t.is_completed
+			  FROM todo_dependencies d
+			  JOIN todos t ON t.id = d.todo_id
+			  WHERE d.blocker_id = ANY($1)
+			    AND t.archived_at IS NULL
+			    AND todo_role(t.id, $2) IS NOT NULL
+			ORDER BY name`
+
+	var rows []struct {
+		TodoID    string `db:"todo_id"`
+		BlockedBy bool   `db:"blocked_by"`
+		models.TodoLink
+	}
+	if getErr := database.Todo.Select(&rows, SQL, pq.Array(todoIDs), userID); getErr != nil {
+		return getErr
+	}
+
+	index := make(map[string]int, len(todos))
+	for i := range todos {
+		index[todos[i].ID] = i
+	}
+	for _, row := range rows {
+		i := index[row.TodoID]
+		if row.BlockedBy {
+			todos[i].BlockedBy = append(todos[i].BlockedBy, row.TodoLink)
+		} else {
+			todos[i].Blocks = append(todos[i].Blocks, row.TodoLink)
+		}
+	}
+	return nil
+}
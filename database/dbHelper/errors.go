@@ -0,0 +1,158 @@
+package dbHelper
+
+import (
+	"Todo/models"
+	"Todo/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	uniqueViolation = "23505"
+	checkViolation  = "23514"
+
+	// wipLimitViolation is raised by the check_wip_limit trigger.
+	wipLimitViolation = "TD001"
+)
+
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrAlreadyArchived = errors.New("already archived")
+	ErrInvalidUUID     = errors.New("invalid uuid")
+
+	ErrTodoAlreadyExists  = errors.New("todo already exists")
+	ErrInvalidSchedule    = errors.New("start date must not be after due date")
+	ErrRecurrenceDueDate  = errors.New("recurring todos need a due date")
+	ErrCursorMismatch     = errors.New("cursor does not match the requested sort order")
+	ErrTagAlreadyExists   = errors.New("tag already exists")
+	ErrTagNotFound        = errors.New("tag not found")
+	ErrBlockerNotFound    = errors.New("blocker not found")
+	ErrDependencyCycle    = errors.New("dependency would create a cycle")
+	ErrTodoBlocked        = errors.New("todo is blocked by open todos")
+	ErrCommentNotFound    = errors.New("comment not found")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrAssigneeNoAccess   = errors.New("assignee has no access to the todo")
+
+	ErrProjectAlreadyExists = errors.New("project already exists")
+	ErrFilterAlreadyExists  = errors.New("filter already exists")
+
+	ErrStatusAlreadyExists = errors.New("status already exists")
+	ErrStatusNotFound      = errors.New("status not found")
+	ErrLastStatus          = errors.New("a workflow needs at least one todo and one done status")
+	ErrStatusOrderMismatch = errors.New("status order must list every status of the workflow once")
+	ErrWIPLimitReached     = errors.New("status has reached its work in progress limit")
+
+	ErrForbidden          = errors.New("forbidden")
+	ErrShareUserNotFound  = errors.New("no user with this email")
+	ErrShareWithSelf      = errors.New("cannot share with yourself")
+	ErrShareAlreadyExists = errors.New("already shared with this user")
+	ErrShareNotFound      = errors.New("share not found")
+
+	ErrMemberNotFound     = errors.New("member not found")
+	ErrAlreadyMember      = errors.New("already a member of the workspace")
+	ErrAlreadyInvited     = errors.New("already invited to the workspace")
+	ErrInvitationNotFound = errors.New("invitation not found")
+)
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+func isCheckViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == checkViolation && pqErr.Constraint == constraint
+}
+
+func isWIPLimitViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == wipLimitViolation
+}
+
+// expectAffected turns a statement that matched no rows into ErrNotFound.
+func expectAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// notFound maps the sql.ErrNoRows of a single row query to ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// validateUUIDs rejects malformed ids before they reach Postgres, which would
+// otherwise fail the whole query with an invalid input syntax error.
+func validateUUIDs(ids ...string) error {
+	for _, id := range ids {
+		if !utils.IsUUID(id) {
+			return fmt.Errorf("%w: %q", ErrInvalidUUID, id)
+		}
+	}
+	return nil
+}
+
+// todoMissing explains why a statement on a live todo matched nothing: the
+// todo is archived, the user may only read it, or it does not exist for this
+// user. Todos the user has no access to are reported as not found so that
+// their existence is not revealed.
+func todoMissing(db sqlx.Ext, todoID, userID string, err error) error {
+	if !errors.Is(err, ErrNotFound) && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	SQL := `SELECT archived_at IS NOT NULL AS is_archived,
+			       todo_role(id, $2) AS role
+			  FROM todos
+			  WHERE id = $1`
+
+	var todo struct {
+		Archived bool    `db:"is_archived"`
+		Role     *string `db:"role"`
+	}
+	if chkErr := sqlx.Get(db, &todo, SQL, todoID, userID); chkErr != nil {
+		return notFound(chkErr)
+	}
+	switch {
+	case todo.Role == nil:
+		return ErrNotFound
+	case todo.Archived:
+		return ErrAlreadyArchived
+	case *todo.Role != string(models.RoleOwner):
+		return ErrForbidden
+	}
+	return ErrNotFound
+}
+
+// missingOnTodo explains why a statement on a comment or attachment of a todo
+// matched nothing: the todo is archived or unknown, or else the item itself
+// is gone and missing is returned.
+func missingOnTodo(db sqlx.Ext, todoID, userID string, missing error) error {
+	SQL := `SELECT archived_at IS NULL
+			  FROM todos
+			  WHERE id = $1
+			    AND todo_role(id, $2) IS NOT NULL`
+
+	var live bool
+	if chkErr := sqlx.Get(db, &live, SQL, todoID, userID); chkErr != nil {
+		return notFound(chkErr)
+	}
+	if !live {
+		return ErrAlreadyArchived
+	}
+	return missing
+}
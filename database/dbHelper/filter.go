@@ -0,0 +1,84 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"Todo/models"
+)
+
+const savedFilterColumns = `id, name, criteria, created_at`
+
+func CreateSavedFilter(userID string, body models.SavedFilterRequest) (models.SavedFilter, error) {
+	SQL := `INSERT INTO saved_filters (user_id, name, criteria)
+			  VALUES ($1, TRIM($2), $3)
+			  RETURNING ` + savedFilterColumns
+
+	var filter models.SavedFilter
+	crtErr := database.Todo.Get(&filter, SQL, userID, body.Name, body.Criteria)
+	if isUniqueViolation(crtErr) {
+		return filter, ErrFilterAlreadyExists
+	}
+	return filter, crtErr
+}
+
+func GetAllSavedFilters(userID string) ([]models.SavedFilter, error) {
+	SQL := `SELECT ` + savedFilterColumns + `
+			  FROM saved_filters
+			  WHERE user_id = $1
+			    AND archived_at IS NULL
+			  ORDER BY name`
+
+	filters := make([]models.SavedFilter, 0)
+	getErr := database.Todo.Select(&filters, SQL, userID)
+	return filters, getErr
+}
+
+func GetSavedFilter(filterID, userID string) (models.SavedFilter, error) {
+	SQL := `SELECT ` + savedFilterColumns + `
+			  FROM saved_filters
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND archived_at IS NULL`
+
+	var filter models.SavedFilter
+	if err := validateUUIDs(filterID); err != nil {
+		return filter, err
+	}
+
+	getErr := database.Todo.Get(&filter, SQL, filterID, userID)
+	return filter, notFound(getErr)
+}
+
+func UpdateSavedFilter(filterID, userID string, body models.SavedFilterRequest) (models.SavedFilter, error) {
+	SQL := `UPDATE saved_filters
+			  SET name = TRIM($3),
+			      criteria = $4
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND archived_at IS NULL
+			  RETURNING ` + savedFilterColumns
+
+	var filter models.SavedFilter
+	if err := validateUUIDs(filterID); err != nil {
+		return filter, err
+	}
+
+	updErr := database.Todo.Get(&filter, SQL, filterID, userID, body.Name, body.Criteria)
+	if isUniqueViolation(updErr) {
+		return filter, ErrFilterAlreadyExists
+	}
+	return filter, notFound(updErr)
+}
+
+func DeleteSavedFilter(filterID, userID string) error {
+	SQL := `UPDATE saved_filters
+			  SET archived_at = NOW()
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND archived_at IS NULL`
+
+	if err := validateUUIDs(filterID); err != nil {
+		return err
+	}
+
+	return expectAffected(database.Todo.Exec(SQL, filterID, userID))
+}
@@ -0,0 +1,13 @@
+package dbHelper
+
+import "github.com/jmoiron/sqlx"
+
+// lockUser serializes transactions that change the same kind of data of a
+// user until the surrounding transaction ends. The scope names that kind so
+// that unrelated changes do not wait for each other.
+func lockUser(db sqlx.Ext, scope, userID string) error {
+	SQL := `SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`
+
+	_, lockErr := db.Exec(SQL, scope, userID)
+	return lockErr
+}
@@ -0,0 +1,168 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"Todo/position"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// positionScope is the lock scope of every change to the order of a user's
+// list: new keys, moves and rebalances. It is taken before any todo of the
+// list is locked, since a rebalance locks all of them.
+const positionScope = "todo_positions"
+
+// LockTodoPositions serializes changes to the order of the list that holds a
+// todo, which is the list of its owner, until the surrounding transaction
+// ends. A missing todo is left for LockTodo to report.
+func LockTodoPositions(db sqlx.Ext, todoID string) error {
+	SQL := `SELECT CAST(user_id AS TEXT)
+			  FROM todos
+			  WHERE id = $1`
+
+	if err := validateUUIDs(todoID); err != nil {
+		return err
+	}
+
+	var ownerID string
+	getErr := sqlx.Get(db, &ownerID, SQL, todoID)
+	if errors.Is(getErr, sql.ErrNoRows) {
+		return nil
+	}
+	if getErr != nil {
+		return getErr
+	}
+	return lockUser(db, positionScope, ownerID)
+}
+
+// GetTodoPosition returns the position of a live todo of the user.
+func GetTodoPosition(db sqlx.Ext, todoID, userID string) (string, error) {
+	SQL := `SELECT position
+			  FROM todos
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND archived_at IS NULL`
+
+	if err := validateUUIDs(todoID); err != nil {
+		return "", err
+	}
+
+	var key string
+	getErr := sqlx.Get(db, &key, SQL, todoID, userID)
+	return key, todoMissing(db, todoID, userID, getErr)
+}
+
+// NextTodoPosition returns the first position after key in the user's list,
+// or "" at the end of the list. The todo being moved is skipped.
+func NextTodoPosition(db sqlx.Ext, userID, key, excludeID string) (string, error) {
+	SQL := `SELECT COALESCE(min(position), '')
+			  FROM todos
+			  WHERE user_id = $1
+			    AND archived_at IS NULL
+			    AND position > $2
+			    AND id IS DISTINCT FROM CAST(NULLIF($3, '') AS UUID)`
+
+	var next string
+	getErr := sqlx.Get(db, &next, SQL, userID, key, excludeID)
+	return next, getErr
+}
+
+// PreviousTodoPosition returns the last position before key in the user's
+// list, or "" at the start of the list. The todo being moved is skipped.
+func PreviousTodoPosition(db sqlx.Ext, userID, key, excludeID string) (string, error) {
+	SQL := `SELECT COALESCE(max(position), '')
+			  FROM todos
+			  WHERE user_id = $1
+			    AND archived_at IS NULL
+			    AND position < $2
+			    AND id IS DISTINCT FROM CAST(NULLIF($3, '') AS UUID)`
+
+	var previous string
+	getErr := sqlx.Get(db, &previous, SQL, userID, key, excludeID)
+	return previous, getErr
+}
+
+func SetTodoPosition(db sqlx.Ext, todoID, userID, key string) error {
+	SQL := `UPDATE todos
+			  SET position = $3
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND archived_at IS NULL`
+
+	if err := validateUUIDs(todoID); err != nil {
+		return err
+	}
+
+	updErr := expectAffected(db.Exec(SQL, todoID, userID, key))
+	return todoMissing(db, todoID, userID, updErr)
+}
+
+// RebalanceTodoPositions rewrites the positions of all live todos of a user
+// with short, evenly spaced keys, keeping their order. Todos sharing a
+// position keep their id order, which is how lists break such ties.
+func RebalanceTodoPositions(db sqlx.Ext, userID string) error {
+	if lockErr := lockUser(db, positionScope, userID); lockErr != nil {
+		return lockErr
+	}
+
+	SQL := `SELECT id
+			  FROM todos
+			  WHERE user_id = $1
+			    AND archived_at IS NULL
+			  ORDER BY position, id
+			  FOR UPDATE`
+
+	todoIDs := make([]string, 0)
+	if getErr := sqlx.Select(db, &todoIDs, SQL, userID); getErr != nil {
+		return getErr
+	}
+
+	SQL = `UPDATE todos
+			 SET position = balanced.position
+			 FROM unnest(CAST($1 AS UUID[]), CAST($2 AS TEXT[])) AS balanced (id, position)
+			 WHERE todos.id = balanced.id`
+
+	_, updErr := db.Exec(SQL, pq.Array(todoIDs), pq.Array(position.Spread(len(todoIDs))))
+	return updErr
+}
+
+// GetUsersToRebalance lists users whose todo positions grew longer than
+// maxLength or collide, both of which RebalanceTodoPositions fixes. Users come
+// in id order, starting after the given id unless it is empty.
+func GetUsersToRebalance(maxLength int, after string, limit int) ([]string, error) {
+	SQL := `SELECT user_id
+			  FROM todos
+			  WHERE archived_at IS NULL
+			    AND ($2 = '' OR user_id > CAST(NULLIF($2, '') AS UUID))
+			  GROUP BY user_id
+			  HAVING max(length(position)) > $1
+			      OR count(*) <> count(DISTINCT position)
+			  ORDER BY user_id
+			  LIMIT $3`
+
+	userIDs := make([]string, 0)
+	getErr := database.Todo.Select(&userIDs, SQL, maxLength, after, limit)
+	return userIDs, getErr
+}
+
+// appendTodoPosition returns a position at the end of the user's list. The
+// list stays locked until the transaction ends, so that concurrent inserts
+// cannot take the same position.
+func appendTodoPosition(db sqlx.Ext, userID string) (string, error) {
+	SQL := `SELECT COALESCE(max(position), '')
+			  FROM todos
+			  WHERE user_id = $1
+			    AND archived_at IS NULL`
+
+	if lockErr := lockUser(db, positionScope, userID); lockErr != nil {
+		return "", lockErr
+	}
+
+	var last string
+	if getErr := sqlx.Get(db, &last, SQL, userID); getErr != nil {
+		return "", getErr
+	}
+	return position.Between(last, "")
+}
@@ -0,0 +1,179 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"Todo/models"
+	"errors"
+	"github.com/jmoiron/sqlx"
+)
+
+const inboxProjectName = "Inbox"
+
+const projectColumns = `id, name, is_inbox, workspace_id`
+
+// projectManager matches the projects $2 may rename and delete: their own and,
+// as owner or admin, those of a workspace.
+const projectManager = `(user_id = $2 OR workspace_role(workspace_id, $2) IN ('owner', 'admin'))`
+
+func IsProjectOwned(projectID, userID string) (bool, error) {
+	SQL := `SELECT count(id) > 0 as is_exist
+			  FROM projects
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND archived_at IS NULL`
+
+	if err := validateUUIDs(projectID); err != nil {
+		return false, err
+	}
+
+	var check bool
+	chkErr := database.Todo.Get(&check, SQL, projectID, userID)
+	return check, chkErr
+}
+
+// CreateProject creates a personal project, or a project of a workspace when
+// workspaceID is given.
+func CreateProject(db sqlx.Ext, userID, workspaceID, name string) (models.Project, error) {
+	SQL := `INSERT INTO projects (user_id, workspace_id, name)
+			  VALUES ($1, CAST(NULLIF($2, '') AS UUID), TRIM($3))
+			  RETURNING ` + projectColumns
+
+	var project models.Project
+	crtErr := sqlx.Get(db, &project, SQL, userID, workspaceID, name)
+	if isUniqueViolation(crtErr) {
+		return project, ErrProjectAlreadyExists
+	}
+	return project, crtErr
+}
+
+func CreateInboxProject(db sqlx.Ext, userID string) error {
+	SQL := `INSERT INTO projects (user_id, name, is_inbox)
+			  VALUES ($1, $2, TRUE)`
+
+	_, crtErr := db.Exec(SQL, userID, inboxProjectName)
+	return crtErr
+}
+
+func GetInboxProjectID(userID string) (string, error) {
+	SQL := `SELECT id
+			  FROM projects
+			  WHERE user_id = $1
+			    AND is_inbox
+			    AND archived_at IS NULL`
+
+	var projectID string
+	getErr := database.Todo.Get(&projectID, SQL, userID)
+	return projectID, getErr
+}
+
+// GetAllProjects lists the personal projects of a user, or every project of a
+// workspace when workspaceID is given.
+func GetAllProjects(userID, workspaceID string) ([]models.Project, error) {
+	SQL := `SELECT ` + projectColumns + `
+			  FROM projects
+			  WHERE workspace_id IS NOT DISTINCT FROM CAST(NULLIF($2, '') AS UUID)
+			    AND ($2 <> '' OR user_id = $1)
+			    AND archived_at IS NULL
+			  ORDER BY is_inbox DESC, name`
+
+	projects := make([]models.Project, 0)
+	getErr := database.Todo.Select(&projects, SQL, userID, workspaceID)
+	return projects, getErr
+}
+
+// LockProject returns a live project the user may rename and delete and locks
+// it until the end of the transaction, so that a change can be logged with
+// the project before it.
+func LockProject(db sqlx.Ext, projectID, userID string) (models.Project, error) {
+	SQL := `SELECT ` + projectColumns + `
+			  FROM projects
+			  WHERE id = $1
+			    AND ` + projectManager + `
+			    AND archived_at IS NULL
+			  FOR UPDATE`
+
+	var project models.Project
+	if err := validateUUIDs(projectID); err != nil {
+		return project, err
+	}
+
+	if getErr := sqlx.Get(db, &project, SQL, projectID, userID); getErr != nil {
+		return project, projectMissing(projectID, userID, notFound(getErr))
+	}
+	return project, nil
+}
+
+func RenameProject(db sqlx.Ext, projectID, userID, name string) (models.Project, error) {
+	SQL := `UPDATE projects
+			  SET name = TRIM($3)
+			  WHERE id = $1
+			    AND ` + projectManager + `
+			    AND archived_at IS NULL
+			  RETURNING ` + projectColumns
+
+	var project models.Project
+	if err := validateUUIDs(projectID); err != nil {
+		return project, err
+	}
+
+	updErr := sqlx.Get(db, &project, SQL, projectID, userID, name)
+	if isUniqueViolation(updErr) {
+		return project, ErrProjectAlreadyExists
+	}
+	if updErr != nil {
+		return project, projectMissing(projectID, userID, notFound(updErr))
+	}
+	return project, nil
+}
+
+// DeleteProject archives a project along with its todos and returns it. The
+// inbox cannot be deleted since it is where todos without a project end up.
+func DeleteProject(db sqlx.Ext, projectID, userID string) (models.Project, error) {
+	SQL := `UPDATE projects
+			  SET archived_at = NOW()
+			  WHERE id = $1
+			    AND ` + projectManager + `
+			    AND NOT is_inbox
+			    AND archived_at IS NULL
+			  RETURNING ` + projectColumns
+
+	var project models.Project
+	if err := validateUUIDs(projectID); err != nil {
+		return project, err
+	}
+
+	if delErr := sqlx.Get(db, &project, SQL, projectID, userID); delErr != nil {
+		return project, projectMissing(projectID, userID, notFound(delErr))
+	}
+
+	SQL = `UPDATE todos
+			 SET archived_at = NOW()
+			 WHERE project_id = $1
+			   AND archived_at IS NULL`
+
+	_, delErr := db.Exec(SQL, projectID)
+	return project, delErr
+}
+
+// projectMissing tells members of a workspace who may not manage one of its
+// projects apart from users who cannot see the project at all.
+func projectMissing(projectID, userID string, err error) error {
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	SQL := `SELECT count(id) > 0 AS is_exist
+			  FROM projects
+			  WHERE id = $1
+			    AND archived_at IS NULL
+			    AND workspace_role(workspace_id, $2) IS NOT NULL`
+
+	var member bool
+	if chkErr := database.Todo.Get(&member, SQL, projectID, userID); chkErr != nil {
+		return chkErr
+	}
+	if member {
+		return ErrForbidden
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,118 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"github.com/lib/pq"
+	"time"
+)
+
+// PurgeArchivedTodos permanently deletes up to limit todos that were archived
+// before the cutoff. Only todos without subtasks are picked so that parents go
+// in a later batch, after their children.
+func PurgeArchivedTodos(cutoff time.Time, limit int) (int64, error) {
+	SQL := `WITH batch AS (
+				SELECT id
+				  FROM todos
+				  WHERE archived_at < $1
+				    AND NOT EXISTS (SELECT 1
+				                      FROM todos c
+				                      WHERE c.parent_id = todos.id)
+				  ORDER BY archived_at
+				  LIMIT $2
+				  FOR UPDATE SKIP LOCKED
+			), tags AS (
+				DELETE FROM todo_tags
+				  WHERE todo_id IN (SELECT id FROM batch)
+			), dependencies AS (
+				DELETE FROM todo_dependencies
+				  WHERE todo_id IN (SELECT id FROM batch)
+				     OR blocker_id IN (SELECT id FROM batch)
+			), comments AS (
+				DELETE FROM todo_comments
+				  WHERE todo_id IN (SELECT id FROM batch)
+			), attachments AS (
+				DELETE FROM todo_attachments
+				  WHERE todo_id IN (SELECT id FROM batch)
+				  RETURNING storage_key
+			), blobs AS (
+				INSERT INTO blob_deletions (storage_key)
+				  SELECT storage_key
+				    FROM attachments
+				  ON CONFLICT DO NOTHING
+			), shared AS (
+				DELETE FROM shares
+				  WHERE todo_id IN (SELECT id FROM batch)
+			)
+			DELETE FROM todos
+			  WHERE id IN (SELECT id FROM batch)`
+
+	res, delErr := database.Todo.Exec(SQL, cutoff, limit)
+	if delErr != nil {
+		return 0, delErr
+	}
+	return res.RowsAffected()
+}
+
+func PurgeArchivedSessions(cutoff time.Time, limit int) (int64, error) {
+	SQL := `DELETE FROM user_session
+			  WHERE id IN (SELECT id
+			                 FROM user_session
+			                 WHERE archived_at < $1
+			                 ORDER BY archived_at
+			                 LIMIT $2
+			                 FOR UPDATE SKIP LOCKED)`
+
+	res, delErr := database.Todo.Exec(SQL, cutoff, limit)
+	if delErr != nil {
+		return 0, delErr
+	}
+	return res.RowsAffected()
+}
+
+// PurgeArchivedAttachments permanently deletes up to limit attachments that
+// were archived before the cutoff and queues their blobs for deletion.
+func PurgeArchivedAttachments(cutoff time.Time, limit int) (int64, error) {
+	SQL := `WITH batch AS (
+				DELETE FROM todo_attachments
+				  WHERE id IN (SELECT id
+				                 FROM todo_attachments
+				                 WHERE archived_at < $1
+				                 ORDER BY archived_at
+				                 LIMIT $2
+				                 FOR UPDATE SKIP LOCKED)
+				  RETURNING storage_key
+			)
+			INSERT INTO blob_deletions (storage_key)
+			  SELECT storage_key
+			    FROM batch
+			  ON CONFLICT DO NOTHING`
+
+	res, delErr := database.Todo.Exec(SQL, cutoff, limit)
+	if delErr != nil {
+		return 0, delErr
+	}
+	return res.RowsAffected()
+}
+
+// GetBlobDeletions returns up to limit storage keys of blobs that no row
+// refers to anymore.
+func GetBlobDeletions(limit int) ([]string, error) {
+	SQL := `SELECT storage_key
+			  FROM blob_deletions
+			  ORDER BY created_at
+			  LIMIT $1`
+
+	keys := make([]string, 0)
+	getErr := database.Todo.Select(&keys, SQL, limit)
+	return keys, getErr
+}
+
+// RemoveBlobDeletions drops storage keys from the queue once their blobs are
+// gone.
+func RemoveBlobDeletions(keys []string) error {
+	SQL := `DELETE FROM blob_deletions
+			  WHERE storage_key = ANY($1)`
+
+	_, delErr := database.Todo.Exec(SQL, pq.Array(keys))
+	return delErr
+}
@@ -0,0 +1,267 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"Todo/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+)
+
+const shareColumns = `s.id, s.todo_id, s.project_id, s.user_id, u.name AS user_name, u.email AS user_email,
+			s.role, s.created_at`
+
+// shareColumnNames maps a share target to the column of shares that refers
+// to it. Only these names ever reach a query.
+var shareColumnNames = map[models.ShareTarget]string{
+	models.ShareTargetTodo:    "todo_id",
+	models.ShareTargetProject: "project_id",
+}
+
+// CreateShare gives the registered user with the given email access to a
+// todo or project of ownerID.
+func CreateShare(db sqlx.Ext, target models.ShareTarget, itemID, ownerID string, body models.ShareRequest) (models.Share, error) {
+	var share models.Share
+	column, err := checkShareOwner(target, itemID, ownerID)
+	if err != nil {
+		return share, err
+	}
+
+	SQL := `SELECT id
+			  FROM users
+			  WHERE email = TRIM($1)
+			    AND archived_at IS NULL`
+
+	var userID string
+	if getErr := sqlx.Get(db, &userID, SQL, body.Email); getErr != nil {
+		if errors.Is(getErr, sql.ErrNoRows) {
+			return share, ErrShareUserNotFound
+		}
+		return share, getErr
+	}
+	if userID == ownerID {
+		return share, ErrShareWithSelf
+	}
+
+	SQL = fmt.Sprintf(`WITH s AS (
+				INSERT INTO shares (owner_id, user_id, %s, role)
+				  VALUES ($1, $2, $3, $4)
+				  RETURNING *
+			)
+			SELECT `+shareColumns+`
+			  FROM s
+			  JOIN users u ON u.id = s.user_id`, column)
+
+	crtErr := sqlx.Get(db, &share, SQL, ownerID, userID, itemID, body.Role)
+	if isUniqueViolation(crtErr) {
+		return share, ErrShareAlreadyExists
+	}
+	return share, crtErr
+}
+
+// GetShares lists who a todo or project of ownerID is shared with.
+func GetShares(target models.ShareTarget, itemID, ownerID string) ([]models.Share, error) {
+	column, err := checkShareOwner(target, itemID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	SQL := fmt.Sprintf(`SELECT `+shareColumns+`
+			  FROM shares s
+			  JOIN users u ON u.id = s.user_id
+			  WHERE s.%s = $1
+			    AND s.archived_at IS NULL
+			  ORDER BY u.name, s.id`, column)
+
+	shares := make([]models.Share, 0)
+	getErr := database.Todo.Select(&shares, SQL, itemID)
+	return shares, getErr
+}
+
+// LockShare returns a live share of a todo or project of ownerID and locks it
+// until the end of the transaction, so that a change can be logged with the
+// share before it.
+func LockShare(db sqlx.Ext, shareID string, target models.ShareTarget, itemID, ownerID string) (models.Share, error) {
+	var share models.Share
+	column, err := checkShareOwner(target, itemID, ownerID)
+	if err != nil {
+		return share, err
+	}
+	if err = validateUUIDs(shareID); err != nil {
+		return share, err
+	}
+
+	SQL := fmt.Sprintf(`SELECT `+shareColumns+`
+			  FROM shares s
+			  JOIN users u ON u.id = s.user_id
+			  WHERE s.id = $1
+			    AND s.%s = $2
+			    AND s.owner_id = $3
+			    AND s.archived_at IS NULL
+			  FOR UPDATE OF s`, column)
+
+	getErr := sqlx.Get(db, &share, SQL, shareID, itemID, ownerID)
+	if errors.Is(getErr, sql.ErrNoRows) {
+		return share, ErrShareNotFound
+	}
+	return share, getErr
+}
+
+func UpdateShare(db sqlx.Ext, shareID string, target models.ShareTarget, itemID, ownerID string, role models.Role) (models.Share, error) {
+	var share models.Share
+	column, err := checkShareOwner(target, itemID, ownerID)
+	if err != nil {
+		return share, err
+	}
+	if err = validateUUIDs(shareID); err != nil {
+		return share, err
+	}
+
+	SQL := fmt.Sprintf(`WITH s AS (
+				UPDATE shares
+				  SET role = $4
+				  WHERE id = $1
+				    AND %s = $2
+				    AND owner_id = $3
+				    AND archived_at IS NULL
+				  RETURNING *
+			)
+			SELECT `+shareColumns+`
+			  FROM s
+			  JOIN users u ON u.id = s.user_id`, column)
+
+	updErr := sqlx.Get(db, &share, SQL, shareID, itemID, ownerID, role)
+	if errors.Is(updErr, sql.ErrNoRows) {
+		return share, ErrShareNotFound
+	}
+	return share, updErr
+}
+
+// DeleteShare archives a share, which takes the access away right away, and
+// returns it.
+func DeleteShare(db sqlx.Ext, shareID string, target models.ShareTarget, itemID, ownerID string) (models.Share, error) {
+	var share models.Share
+	column, err := checkShareOwner(target, itemID, ownerID)
+	if err != nil {
+		return share, err
+	}
+	if err = validateUUIDs(shareID); err != nil {
+		return share, err
+	}
+
+	SQL := fmt.Sprintf(`WITH s AS (
+				UPDATE shares
+				  SET archived_at = NOW()
+				  WHERE id = $1
+				    AND %s = $2
+				    AND owner_id = $3
+				    AND archived_at IS NULL
+				  RETURNING *
+			)
+			SELECT `+shareColumns+`
+			  FROM s
+			  JOIN users u ON u.id = s.user_id`, column)
+
+	delErr := sqlx.Get(db, &share, SQL, shareID, itemID, ownerID)
+	if errors.Is(delErr, sql.ErrNoRows) {
+		return share, ErrShareNotFound
+	}
+	return share, delErr
+}
+
+// GetTodoRole returns the access a user has to a live todo.
+func GetTodoRole(todoID, userID string) (models.Role, error) {
+	SQL := `SELECT todo_role(id, $2)
+			  FROM todos
+			  WHERE id = $1
+			    AND archived_at IS NULL
+			    AND todo_role(id, $2) IS NOT NULL`
+
+	if err := validateUUIDs(todoID); err != nil {
+		return "", err
+	}
+
+	var role models.Role
+	getErr := database.Todo.Get(&role, SQL, todoID, userID)
+	if getErr != nil {
+		return role, todoMissing(database.Todo, todoID, userID, getErr)
+	}
+	return role, nil
+}
+
+// GetProjectAccess returns the access a user has to the todos of a live
+// project, along with its owner and workspace. Users without access get
+// ErrNotFound.
+func GetProjectAccess(projectID, userID string) (models.ProjectAccess, error) {
+	SQL := `SELECT p.user_id,
+			       p.workspace_id,
+			       CASE WHEN p.user_id = $2 THEN 'owner'
+			            ELSE (SELECT CAST(max(role) AS TEXT)
+			                    FROM (SELECT role
+			                            FROM shares
+			                            WHERE project_id = p.id
+			                              AND user_id = $2
+			                              AND archived_at IS NULL
+			                          UNION ALL
+			                          SELECT member_share_role(workspace_role(p.workspace_id, $2))
+			                            WHERE workspace_role(p.workspace_id, $2) IS NOT NULL) roles)
+			       END AS role
+			  FROM projects p
+			  WHERE p.id = $1
+			    AND p.archived_at IS NULL`
+
+	var access models.ProjectAccess
+	if err := validateUUIDs(projectID); err != nil {
+		return access, err
+	}
+
+	var row struct {
+		models.ProjectAccess
+		Role *models.Role `db:"role"`
+	}
+	if getErr := database.Todo.Get(&row, SQL, projectID, userID); getErr != nil {
+		return access, notFound(getErr)
+	}
+	if row.Role == nil {
+		return access, ErrNotFound
+	}
+	access = row.ProjectAccess
+	access.Role = *row.Role
+	return access, nil
+}
+
+// checkShareOwner makes sure the item of a share exists and belongs to
+// ownerID, since only owners manage who else has access.
+func checkShareOwner(target models.ShareTarget, itemID, ownerID string) (string, error) {
+	column, ok := shareColumnNames[target]
+	if !ok {
+		return "", fmt.Errorf("unsupported share target %q", target)
+	}
+	if err := validateUUIDs(itemID); err != nil {
+		return "", err
+	}
+
+	if target == models.ShareTargetProject {
+		owned, err := IsProjectOwned(itemID, ownerID)
+		if err != nil {
+			return "", err
+		}
+		if !owned {
+			return "", ErrNotFound
+		}
+		return column, nil
+	}
+
+	SQL := `SELECT id
+			  FROM todos
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND archived_at IS NULL`
+
+	var todoID string
+	if getErr := database.Todo.Get(&todoID, SQL, itemID, ownerID); getErr != nil {
+		return "", todoMissing(database.Todo, itemID, ownerID, getErr)
+	}
+	return column, nil
+}
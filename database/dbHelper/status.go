@@ -0,0 +1,377 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"Todo/models"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const statusColumns = `id, project_id, name, category, position, wip_limit`
+
+// statusScope is the lock scope of every change to the workflows of a user.
+const statusScope = "statuses"
+
+// CreateDefaultStatuses sets up the default workflow of a new user.
+func CreateDefaultStatuses(db sqlx.Ext, userID string) error {
+	SQL := `INSERT INTO statuses (user_id, name, category, position)
+			  VALUES ($1, 'To do', 'todo', 1),
+			         ($1, 'In progress', 'doing', 2),
+			         ($1, 'Done', 'done', 3)`
+
+	_, crtErr := db.Exec(SQL, userID)
+	return crtErr
+}
+
+// GetStatuses lists the workflow used by the todos of a project, or the
+// user's default workflow when projectID is empty.
+func GetStatuses(userID, projectID string) ([]models.Status, error) {
+	SQL := `SELECT ` + statusColumns + `
+			  FROM statuses
+			  WHERE user_id = $1
+			    AND project_id IS NOT DISTINCT FROM workflow_project($1, CAST(NULLIF($2, '') AS UUID))
+			    AND archived_at IS NULL
+			  ORDER BY position`
+
+	statuses := make([]models.Status, 0)
+	getErr := database.Todo.Select(&statuses, SQL, userID, projectID)
+	return statuses, getErr
+}
+
+// GetWorkflowStatus returns a status if it belongs to the workflow used by the
+// todos of a project.
+func GetWorkflowStatus(db sqlx.Ext, statusID, userID, projectID string) (models.Status, error) {
+	SQL := `SELECT ` + statusColumns + `
+			  FROM statuses
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND project_id IS NOT DISTINCT FROM workflow_project($2, $3)
+			    AND archived_at IS NULL`
+
+	var status models.Status
+	if err := validateUUIDs(statusID); err != nil {
+		return status, err
+	}
+
+	getErr := sqlx.Get(db, &status, SQL, statusID, userID, projectID)
+	if errors.Is(getErr, sql.ErrNoRows) {
+		return status, ErrStatusNotFound
+	}
+	return status, getErr
+}
+
+// CreateStatus appends a status to a workflow. The first status created for a
+// project starts the project's own workflow as a copy of the default one, so
+// that every workflow has a todo and a done status.
+func CreateStatus(db sqlx.Ext, userID string, body models.StatusRequest) (models.Status, error) {
+	var status models.Status
+	if lockErr := lockUser(db, statusScope, userID); lockErr != nil {
+		return status, lockErr
+	}
+
+	if body.ProjectID != "" {
+		if err := startProjectWorkflow(db, userID, body.ProjectID); err != nil {
+			return status, err
+		}
+	}
+
+	SQL := `INSERT INTO statuses (user_id, project_id, name, category, position, wip_limit)
+			  SELECT CAST($1 AS UUID), CAST(NULLIF($2, '') AS UUID), TRIM($3), CAST($4 AS status_category),
+			         COALESCE(max(position), 0) + 1, CAST($5 AS INTEGER)
+			    FROM statuses
+			    WHERE user_id = $1
+			      AND project_id IS NOT DISTINCT FROM CAST(NULLIF($2, '') AS UUID)
+			      AND archived_at IS NULL
+			  RETURNING ` + statusColumns
+
+	crtErr := sqlx.Get(db, &status, SQL, userID, body.ProjectID, body.Name, body.Category, body.WIPLimit)
+	if isUniqueViolation(crtErr) {
+		return status, ErrStatusAlreadyExists
+	}
+	return status, crtErr
+}
+
+// startProjectWorkflow copies the default workflow into a project that has
+// none yet and moves the project's todos over to the copies.
+func startProjectWorkflow(db sqlx.Ext, userID, projectID string) error {
+	SQL := `INSERT INTO statuses (user_id, project_id, name, category, position, wip_limit)
+			  SELECT user_id, CAST($2 AS UUID), name, category, position, wip_limit
+			    FROM statuses
+			    WHERE user_id = $1
+			      AND project_id IS NULL
+			      AND archived_at IS NULL
+			      AND workflow_project($1, $2) IS NULL`
+
+	res, crtErr := db.Exec(SQL, userID, projectID)
+	if crtErr != nil {
+		return crtErr
+	}
+	if copied, err := res.RowsAffected(); err != nil || copied == 0 {
+		return err
+	}
+
+	SQL = `UPDATE todos
+			 SET status_id = copy.id
+			 FROM statuses original
+			 JOIN statuses copy ON copy.name = original.name
+			   AND copy.project_id = $2
+			   AND copy.archived_at IS NULL
+			 WHERE original.id = todos.status_id
+			   AND original.project_id IS NULL
+			   AND todos.project_id = $2
+			   AND todos.user_id = $1`
+
+	return withoutWIPLimits(db, func() error {
+		_, updErr := db.Exec(SQL, userID, projectID)
+		return updErr
+	})
+}
+
+// UpdateStatus renames a status and changes its category and WIP limit. The
+// todos in the status follow the new category, which is refused if it leaves
+// the workflow without a todo or a done status. Lowering the limit below the
+// current number of todos is allowed, it only stops further moves in.
+func UpdateStatus(db sqlx.Ext, statusID, userID string, body models.UpdateStatusRequest) (models.Status, error) {
+	status, lockErr := lockStatus(db, statusID, userID)
+	if lockErr != nil {
+		return status, lockErr
+	}
+
+	if body.Category != status.Category {
+		if err := checkLastStatus(db, status, userID); err != nil {
+			return status, err
+		}
+	}
+
+	SQL := `UPDATE statuses
+			  SET name = TRIM($3),
+			      category = $4,
+			      wip_limit = $5
+			  WHERE id = $1
+			    AND user_id = $2
+			  RETURNING ` + statusColumns
+
+	updErr := sqlx.Get(db, &status, SQL, statusID, userID, body.Name, body.Category, body.WIPLimit)
+	if isUniqueViolation(updErr) {
+		return status, ErrStatusAlreadyExists
+	}
+	if updErr != nil {
+		return status, updErr
+	}
+
+	// touching status_id makes the trigger derive is_completed again
+	SQL = `UPDATE todos
+			 SET status_id = status_id,
+			     completed_at = CASE WHEN $2 = 'done' THEN COALESCE(completed_at, NOW()) END
+			 WHERE status_id = $1`
+
+	_, updErr = db.Exec(SQL, statusID, body.Category)
+	if isUniqueViolation(updErr) {
+		return status, ErrTodoAlreadyExists
+	}
+	return status, updErr
+}
+
+// ReorderStatuses sets the order of a workflow. statusIDs has to list every
+// status of the workflow exactly once.
+func ReorderStatuses(db sqlx.Ext, userID, projectID string, statusIDs []string) ([]models.Status, error) {
+	if err := validateUUIDs(statusIDs...); err != nil {
+		return nil, err
+	}
+	if lockErr := lockUser(db, statusScope, userID); lockErr != nil {
+		return nil, lockErr
+	}
+
+	SQL := `SELECT count(*) = cardinality(CAST($3 AS UUID[]))
+			  FROM statuses
+			  WHERE user_id = $1
+			    AND project_id IS NOT DISTINCT FROM workflow_project($1, CAST(NULLIF($2, '') AS UUID))
+			    AND archived_at IS NULL
+			    AND id = ANY($3)`
+
+	var complete bool
+	if chkErr := sqlx.Get(db, &complete, SQL, userID, projectID, pq.Array(statusIDs)); chkErr != nil {
+		return nil, chkErr
+	}
+	if !complete || countDistinct(statusIDs) != len(statusIDs) {
+		return nil, ErrStatusOrderMismatch
+	}
+
+	SQL = `SELECT count(*)
+			 FROM statuses
+			 WHERE user_id = $1
+			   AND project_id IS NOT DISTINCT FROM workflow_project($1, CAST(NULLIF($2, '') AS UUID))
+			   AND archived_at IS NULL`
+
+	var total int
+	if chkErr := sqlx.Get(db, &total, SQL, userID, projectID); chkErr != nil {
+		return nil, chkErr
+	}
+	if total != len(statusIDs) {
+		return nil, ErrStatusOrderMismatch
+	}
+
+	SQL = `UPDATE statuses
+			 SET position = ordered.position
+			 FROM unnest(CAST($2 AS UUID[])) WITH ORDINALITY AS ordered (id, position)
+			 WHERE statuses.id = ordered.id
+			   AND statuses.user_id = $1`
+
+	if _, updErr := db.Exec(SQL, userID, pq.Array(statusIDs)); updErr != nil {
+		return nil, updErr
+	}
+
+	statuses := make([]models.Status, 0)
+	SQL = `SELECT ` + statusColumns + `
+			 FROM statuses
+			 WHERE id = ANY($1)
+			 ORDER BY position`
+
+	getErr := sqlx.Select(db, &statuses, SQL, pq.Array(statusIDs))
+	return statuses, getErr
+}
+
+// DeleteStatus archives a status. Its todos move to the first remaining
+// status of the same category, or to the first todo status if there is none.
+func DeleteStatus(db sqlx.Ext, statusID, userID string) error {
+	status, lockErr := lockStatus(db, statusID, userID)
+	if lockErr != nil {
+		return lockErr
+	}
+
+	if err := checkLastStatus(db, status, userID); err != nil {
+		return err
+	}
+
+	SQL := `UPDATE statuses
+			  SET archived_at = NOW()
+			  WHERE id = $1
+			    AND user_id = $2`
+
+	if _, delErr := db.Exec(SQL, statusID, userID); delErr != nil {
+		return delErr
+	}
+
+	SQL = `UPDATE todos
+			 SET status_id = COALESCE(
+			         (SELECT id
+			            FROM statuses
+			            WHERE user_id = $2
+			              AND project_id IS NOT DISTINCT FROM $3
+			              AND category = $4
+			              AND archived_at IS NULL
+			            ORDER BY position
+			            LIMIT 1),
+			         (SELECT id
+			            FROM statuses
+			            WHERE user_id = $2
+			              AND project_id IS NOT DISTINCT FROM $3
+			              AND category = 'todo'
+			              AND archived_at IS NULL
+			            ORDER BY position
+			            LIMIT 1))
+			 WHERE status_id = $1`
+
+	return withoutWIPLimits(db, func() error {
+		_, updErr := db.Exec(SQL, statusID, userID, status.ProjectID, status.Category)
+		return updErr
+	})
+}
+
+// SetTodoStatus moves a todo to a status of its workflow, which the caller
+// has checked with GetWorkflowStatus. The completion time follows the
+// category of the status.
+func SetTodoStatus(db sqlx.Ext, todoID, userID string, status models.Status) error {
+	SQL := `UPDATE todos
+			  SET status_id = $3,
+			      completed_at = CASE WHEN $4 = 'done' THEN COALESCE(completed_at, NOW()) END
+			  WHERE id = $1
+			    AND todo_role(id, $2) IN ('owner', 'editor')
+			    AND archived_at IS NULL`
+
+	if err := validateUUIDs(todoID); err != nil {
+		return err
+	}
+
+	updErr := expectAffected(db.Exec(SQL, todoID, userID, status.ID, status.Category))
+	if isUniqueViolation(updErr) {
+		return ErrTodoAlreadyExists
+	}
+	if isWIPLimitViolation(updErr) {
+		return ErrWIPLimitReached
+	}
+	return todoMissing(db, todoID, userID, updErr)
+}
+
+// lockStatus takes the workflow lock of the user and reads a live status.
+func lockStatus(db sqlx.Ext, statusID, userID string) (models.Status, error) {
+	var status models.Status
+	if err := validateUUIDs(statusID); err != nil {
+		return status, err
+	}
+	if lockErr := lockUser(db, statusScope, userID); lockErr != nil {
+		return status, lockErr
+	}
+
+	SQL := `SELECT ` + statusColumns + `
+			  FROM statuses
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND archived_at IS NULL`
+
+	getErr := sqlx.Get(db, &status, SQL, statusID, userID)
+	return status, notFound(getErr)
+}
+
+// checkLastStatus refuses to take away the last todo or done status of a
+// workflow, since completing and reopening todos relies on them.
+func checkLastStatus(db sqlx.Ext, status models.Status, userID string) error {
+	if status.Category == models.StatusCategoryDoing {
+		return nil
+	}
+
+	SQL := `SELECT count(*)
+			  FROM statuses
+			  WHERE user_id = $1
+			    AND project_id IS NOT DISTINCT FROM $2
+			    AND category = $3
+			    AND archived_at IS NULL`
+
+	var count int
+	if chkErr := sqlx.Get(db, &count, SQL, userID, status.ProjectID, status.Category); chkErr != nil {
+		return chkErr
+	}
+	if count <= 1 {
+		return ErrLastStatus
+	}
+	return nil
+}
+
+// OverrideWIPLimits lets the rest of the transaction put todos into statuses
+// that have reached their WIP limit, which the check_wip_limit trigger
+// refuses otherwise.
+func OverrideWIPLimits(db sqlx.Ext) error {
+	return setWIPOverride(db, "on")
+}
+
+// withoutWIPLimits runs a rearrangement of a workflow with the WIP limits
+// lifted. Such changes are not moves by the user and may leave statuses above
+// their limits, like lowering a limit does.
+func withoutWIPLimits(db sqlx.Ext, run func() error) error {
+	if err := setWIPOverride(db, "on"); err != nil {
+		return err
+	}
+	if err := run(); err != nil {
+		return err
+	}
+	return setWIPOverride(db, "off")
+}
+
+func setWIPOverride(db sqlx.Ext, value string) error {
+	SQL := `SELECT set_config('todo.override_wip', $1, true)`
+
+	_, err := db.Exec(SQL, value)
+	return err
+}
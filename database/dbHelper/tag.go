@@ -0,0 +1,207 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"Todo/models"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func CreateTag(db sqlx.Ext, name, userID string) (models.Tag, error) {
+	SQL := `INSERT INTO tags (name, user_id)
+			  VALUES (TRIM($1), $2)
+			  RETURNING id, name`
+
+	var tag models.Tag
+	crtErr := sqlx.Get(db, &tag, SQL, name, userID)
+	if isUniqueViolation(crtErr) {
+		return tag, ErrTagAlreadyExists
+	}
+	return tag, crtErr
+}
+
+func GetAllTags(userID string) ([]models.Tag, error) {
+	SQL := `SELECT id, name
+			  FROM tags
+			  WHERE user_id = $1
+			    AND archived_at IS NULL
+			  ORDER BY name`
+
+	tags := make([]models.Tag, 0)
+	getErr := database.Todo.Select(&tags, SQL, userID)
+	return tags, getErr
+}
+
+// LockTag returns a live tag of the user and locks it until the end of the
+// transaction, so that a change can be logged with the tag before it.
+func LockTag(db sqlx.Ext, tagID, userID string) (models.Tag, error) {
+	SQL := `SELECT id, name
+			  FROM tags
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND archived_at IS NULL
+			  FOR UPDATE`
+
+	var tag models.Tag
+	if err := validateUUIDs(tagID); err != nil {
+		return tag, err
+	}
+
+	getErr := sqlx.Get(db, &tag, SQL, tagID, userID)
+	return tag, notFound(getErr)
+}
+
+func RenameTag(db sqlx.Ext, tagID, userID, name string) (models.Tag, error) {
+	SQL := `UPDATE tags
+			  SET name = TRIM($3)
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND archived_at IS NULL
+			  RETURNING id, name`
+
+	var tag models.Tag
+	if err := validateUUIDs(tagID); err != nil {
+		return tag, err
+	}
+
+	updErr := sqlx.Get(db, &tag, SQL, tagID, userID, name)
+	if isUniqueViolation(updErr) {
+		return tag, ErrTagAlreadyExists
+	}
+	return tag, notFound(updErr)
+}
+
+// DeleteTag archives a live tag of the user and returns it.
+func DeleteTag(db sqlx.Ext, tagID, userID string) (models.Tag, error) {
+	SQL := `UPDATE tags
+			  SET archived_at = NOW()
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND archived_at IS NULL
+			  RETURNING id, name`
+
+	var tag models.Tag
+	if err := validateUUIDs(tagID); err != nil {
+		return tag, err
+	}
+
+	delErr := sqlx.Get(db, &tag, SQL, tagID, userID)
+	return tag, notFound(delErr)
+}
+
+// AttachTags tags a live todo the user may change and returns the IDs of the
+// tags it did not have yet. The tags have to be tags of the todo's owner,
+// since tags belong to the list a todo is in.
+func AttachTags(db sqlx.Ext, todoID, userID string, tagIDs []string) ([]string, error) {
+	if err := validateUUIDs(append([]string{todoID}, tagIDs...)...); err != nil {
+		return nil, err
+	}
+
+	SQL := `SELECT user_id
+			  FROM todos
+			  WHERE id = $1
+			    AND todo_role(id, $2) IN ('owner', 'editor')
+			    AND archived_at IS NULL`
+
+	var ownerID string
+	if chkErr := sqlx.Get(db, &ownerID, SQL, todoID, userID); chkErr != nil {
+		return nil, todoMissing(db, todoID, userID, chkErr)
+	}
+
+	SQL = `SELECT count(DISTINCT id)
+			  FROM tags
+			  WHERE id = ANY($1)
+			    AND user_id = $2
+			    AND archived_at IS NULL`
+
+	var owned int
+	if chkErr := sqlx.Get(db, &owned, SQL, pq.Array(tagIDs), ownerID); chkErr != nil {
+		return nil, chkErr
+	}
+	if owned != countDistinct(tagIDs) {
+		return nil, ErrTagNotFound
+	}
+
+	SQL = `INSERT INTO todo_tags (todo_id, tag_id)
+			 SELECT td.id, t.id
+			   FROM todos td
+			   JOIN tags t ON t.user_id = td.user_id
+			   WHERE td.id = $1
+			     AND todo_role(td.id, $2) IN ('owner', 'editor')
+			     AND td.archived_at IS NULL
+			     AND t.id = ANY($3)
+			 ON CONFLICT DO NOTHING
+			 RETURNING tag_id`
+
+	attached := make([]string, 0, len(tagIDs))
+	crtErr := sqlx.Select(db, &attached, SQL, todoID, userID, pq.Array(tagIDs))
+	return attached, crtErr
+}
+
+// DetachTag removes a tag from a live todo the user may change.
+func DetachTag(db sqlx.Ext, todoID, tagID, userID string) error {
+	SQL := `DELETE FROM todo_tags tt
+			  USING todos td
+			  WHERE tt.todo_id = td.id
+			    AND tt.todo_id = $1
+			    AND tt.tag_id = $2
+			    AND todo_role(td.id, $3) IN ('owner', 'editor')
+			    AND td.archived_at IS NULL`
+
+	if err := validateUUIDs(todoID, tagID); err != nil {
+		return err
+	}
+
+	delErr := expectAffected(db.Exec(SQL, todoID, tagID, userID))
+	if errors.Is(delErr, ErrNotFound) {
+		return missingOnTodo(db, todoID, userID, ErrNotFound)
+	}
+	return delErr
+}
+
+// loadTodoTags fills in the tags of every todo with a single query.
+func loadTodoTags(todos []models.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	todoIDs := make([]string, 0, len(todos))
+	for i := range todos {
+		todoIDs = append(todoIDs, todos[i].ID)
+		todos[i].Tags = make([]models.Tag, 0)
+	}
+
+	SQL := `SELECT tt.todo_id, t.id, t.name
+			  FROM todo_tags tt
+			  JOIN tags t ON t.id = tt.tag_id
+			  WHERE tt.todo_id = ANY($1)
+			    AND t.archived_at IS NULL
+			  ORDER BY t.name`
+
+	var rows []struct {
+		TodoID string `db:"todo_id"`
+		models.Tag
+	}
+	if getErr := database.Todo.Select(&rows, SQL, pq.Array(todoIDs)); getErr != nil {
+		return getErr
+	}
+
+	index := make(map[string]int, len(todos))
+	for i := range todos {
+		index[todos[i].ID] = i
+	}
+	for _, row := range rows {
+		i := index[row.TodoID]
+		todos[i].Tags = append(todos[i].Tags, row.Tag)
+	}
+	return nil
+}
+
+func countDistinct(values []string) int {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		seen[value] = true
+	}
+	return len(seen)
+}
@@ -3,71 +3,856 @@ package dbHelper
 import (
 	"Todo/database"
 	"Todo/models"
+	"Todo/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func IsTodoExists(name, userID string) (bool, error) {
+type todoSortColumn struct {
+	column string
+	cast   string
+	value  func(todo models.Todo) *string
+}
+
+var todoSortColumns = map[models.TodoSortKey]todoSortColumn{
+	models.TodoSortPriority: {
+		column: "priority",
+		cast:   "todo_priority",
+		value:  func(todo models.Todo) *string { return stringPtr(string(todo.Priority)) },
+	},
+	models.TodoSortDueAt: {
+		column: "due_at",
+		cast:   "TIMESTAMPTZ",
+		value:  func(todo models.Todo) *string { return timePtrString(todo.DueAt) },
+	},
+	models.TodoSortCreatedAt: {
+		column: "created_at",
+		cast:   "TIMESTAMPTZ",
+		value:  func(todo models.Todo) *string { return timePtrString(&todo.CreatedAt) },
+	},
+	models.TodoSortName: {
+		column: "name",
+		cast:   "TEXT",
+		value:  func(todo models.Todo) *string { return stringPtr(todo.Name) },
+	},
+	models.TodoSortPosition: {
+		column: "position",
+		cast:   "TEXT",
+		value:  func(todo models.Todo) *string { return stringPtr(todo.Position) },
+	},
+	models.TodoSortRelevance: {
+		column: todoRank,
+		cast:   "REAL",
+		value: func(todo models.Todo) *string {
+			if todo.Rank == nil {
+				return nil
+			}
+			return stringPtr(strconv.FormatFloat(float64(*todo.Rank), 'g', -1, 32))
+		},
+	},
+}
+
+// todoSearchQuery and todoRank refer to the full-text search query, which is
+// always passed as $12 to the statement built by GetAllTodos.
+const (
+	todoSearchQuery = `websearch_to_tsquery('english', $12)`
+	todoRank        = `ts_rank(search_vector, ` + todoSearchQuery + `)`
+)
+
+const todoColumns = `id, user_id, project_id, parent_id, name, description, is_completed, completed_at,
+			assignee_id,
+			(SELECT name FROM users WHERE id = todos.assignee_id) AS assignee_name,
+			status_id,
+			(SELECT name FROM statuses WHERE id = todos.status_id) AS status_name,
+			(SELECT category FROM statuses WHERE id = todos.status_id) AS status_category,
+			due_at, start_at, priority, position, created_at, archived_at,
+			recurrence_rule, recurrence_timezone, recurrence_start, recurrence_index,
+			(SELECT count(*)
+			   FROM todos c
+			   WHERE c.parent_id = todos.id
+			     AND c.archived_at IS NULL) AS subtasks_total,
+			(SELECT count(*)
+			   FROM todos c
+			   WHERE c.parent_id = todos.id
+			     AND c.is_completed
+			     AND c.archived_at IS NULL) AS subtasks_done,
+			(SELECT count(*)
+			   FROM todo_comments c
+			   WHERE c.todo_id = todos.id
+			     AND c.archived_at IS NULL) AS comments_count`
+
+const (
+	scheduleConstraint   = "todo_schedule"
+	recurrenceConstraint = "todo_recurrence"
+)
+
+var (
+	defaultTodoSort   = []models.TodoSort{{Key: models.TodoSortPosition}}
+	defaultSearchSort = []models.TodoSort{{Key: models.TodoSortRelevance, Desc: true}}
+)
+
+func IsTodoExists(db sqlx.Ext, name, projectID string) (bool, error) {
 	SQL := `SELECT count(id) > 0 as is_exist
 			  FROM todos
 			  WHERE name = TRIM($1)     
-			    AND user_id = $2        
-			    AND archived_at IS NULL`
+			    AND project_id = $2     
+			    AND parent_id IS NULL   
+			    AND archived_at IS NULL 
+			    AND (recurrence_rule IS NULL OR NOT is_completed)`
+
+	var check bool
+	chkErr := sqlx.Get(db, &check, SQL, name, projectID)
+	return check, chkErr
+}
+
+func IsSubtaskExists(db sqlx.Ext, name, parentID string) (bool, error) {
+	SQL := `SELECT count(id) > 0 as is_exist
+			  FROM todos
+			  WHERE name = TRIM($1)
+			    AND parent_id = $2
+			    AND archived_at IS NULL
+			    AND (recurrence_rule IS NULL OR NOT is_completed)`
 
 	var check bool
-	chkErr := database.Todo.Get(&check, SQL, name, userID)
+	chkErr := sqlx.Get(db, &check, SQL, name, parentID)
 	return check, chkErr
 }
 
-func CreateTodo(body models.TodoRequest) error {
-	SQL := `INSERT INTO todos (name, description, user_id)
-			  VALUES (TRIM($1), TRIM($2), $3)`
+func CreateTodo(db sqlx.Ext, body models.TodoRequest) (string, error) {
+	SQL := `INSERT INTO todos (name, description, user_id, due_at, start_at, priority, project_id, parent_id,
+			                   recurrence_rule, recurrence_timezone, recurrence_start, position)
+			  VALUES (TRIM($1), TRIM($2), $3, $4, $5, $6, $7, CAST(NULLIF($8, '') AS UUID),
+			          NULLIF($9, ''), NULLIF($10, ''), CASE WHEN $9 = '' THEN NULL ELSE $4 END, $11)
+			  RETURNING id`
+
+	key, keyErr := appendTodoPosition(db, body.UserID)
+	if keyErr != nil {
+		return "", keyErr
+	}
+
+	var todoID string
+	crtErr := sqlx.Get(db, &todoID, SQL, body.Name, body.Description, body.UserID, body.DueAt, body.StartAt,
+		body.Priority, body.ProjectID, body.ParentID, body.RecurrenceRule, body.RecurrenceTimezone, key)
+	switch {
+	case isUniqueViolation(crtErr):
+		return "", ErrTodoAlreadyExists
+	case isWIPLimitViolation(crtErr):
+		return "", ErrWIPLimitReached
+	case isCheckViolation(crtErr, recurrenceConstraint):
+		return "", ErrRecurrenceDueDate
+	case isCheckViolation(crtErr, scheduleConstraint):
+		return "", ErrInvalidSchedule
+	}
+	return todoID, crtErr
+}
+
+// LockTodo reads a todo the user may change and locks its row until the
+// surrounding transaction ends, so that concurrent completions of a recurring
+// todo cannot both schedule its next occurrence.
+func LockTodo(db sqlx.Ext, todoID, userID string) (models.Todo, error) {
+	SQL := `SELECT ` + todoColumns + `
+			  FROM todos
+			  WHERE id = $1
+			    AND todo_role(id, $2) IN ('owner', 'editor')
+			    AND archived_at IS NULL
+			  FOR UPDATE`
+
+	var todo models.Todo
+	if err := validateUUIDs(todoID); err != nil {
+		return todo, err
+	}
+
+	getErr := sqlx.Get(db, &todo, SQL, todoID, userID)
+	return todo, todoMissing(db, todoID, userID, getErr)
+}
+
+// CreateNextOccurrence copies a recurring todo, including its tags, as the
+// next open occurrence of its series at the given position.
+func CreateNextOccurrence(db sqlx.Ext, todoID, position string, dueAt time.Time, startAt *time.Time) (string, error) {
+	SQL := `INSERT INTO todos (user_id, project_id, parent_id, assignee_id, name, description, due_at, start_at, priority,
+			                   recurrence_rule, recurrence_timezone, recurrence_start, recurrence_index, position)
+			  SELECT user_id, project_id, parent_id, assignee_id, name, description, $2, $3, priority,
+			         recurrence_rule, recurrence_timezone, recurrence_start, recurrence_index + 1, $4
+			    FROM todos
+			    WHERE id = $1
+			  RETURNING id`
+
+	var nextID string
+	if crtErr := sqlx.Get(db, &nextID, SQL, todoID, dueAt, startAt, position); crtErr != nil {
+		switch {
+		case isUniqueViolation(crtErr):
+			return "", ErrTodoAlreadyExists
+		case isWIPLimitViolation(crtErr):
+			return "", ErrWIPLimitReached
+		}
+		return "", crtErr
+	}
+
+	SQL = `INSERT INTO todo_tags (todo_id, tag_id)
+			 SELECT $2, tag_id
+			   FROM todo_tags
+			   WHERE todo_id = $1`
+
+	_, crtErr := db.Exec(SQL, todoID, nextID)
+	return nextID, crtErr
+}
+
+// GetTodo returns a live todo the user owns or that is shared with them.
+func GetTodo(todoID, userID string) (models.Todo, error) {
+	SQL := `SELECT ` + todoColumns + `
+			  FROM todos
+			  WHERE id = $1
+			    AND todo_role(id, $2) IS NOT NULL
+			    AND archived_at IS NULL`
+
+	var todo models.Todo
+	if err := validateUUIDs(todoID); err != nil {
+		return todo, err
+	}
+
+	if getErr := database.Todo.Get(&todo, SQL, todoID, userID); getErr != nil {
+		return todo, todoMissing(database.Todo, todoID, userID, getErr)
+	}
+
+	todos := []models.Todo{todo}
+	loadErr := loadTodoRelations(todos, userID)
+	return todos[0], loadErr
+}
+
+// GetSubtaskTree returns every live descendant of a todo nested under its
+// parent, ordered by creation within each level.
+func GetSubtaskTree(todoID, userID string) ([]models.Todo, error) {
+	SQL := `WITH RECURSIVE tree AS (
+				SELECT id
+				  FROM todos
+				  WHERE parent_id = $1
+				    AND todo_role(CAST($1 AS UUID), $2) IS NOT NULL
+				    AND archived_at IS NULL
+				UNION ALL
+				SELECT t.id
+				  FROM todos t
+				  JOIN tree ON t.parent_id = tree.id
+				  WHERE t.archived_at IS NULL
+			)
+			SELECT ` + todoColumns + `
+			  FROM todos
+			  WHERE id IN (SELECT id FROM tree)
+			  ORDER BY position, id`
+
+	if err := validateUUIDs(todoID); err != nil {
+		return nil, err
+	}
+
+	descendants := make([]models.Todo, 0)
+	if getErr := database.Todo.Select(&descendants, SQL, todoID, userID); getErr != nil {
+		return nil, getErr
+	}
+	if loadErr := loadTodoRelations(descendants, userID); loadErr != nil {
+		return nil, loadErr
+	}
+
+	children := make(map[string][]models.Todo)
+	for _, todo := range descendants {
+		children[*todo.ParentID] = append(children[*todo.ParentID], todo)
+	}
+
+	var build func(parentID string) []models.Todo
+	build = func(parentID string) []models.Todo {
+		nodes := children[parentID]
+		for i := range nodes {
+			nodes[i].Subtasks = build(nodes[i].ID)
+		}
+		return nodes
+	}
+
+	tree := build(todoID)
+	if tree == nil {
+		tree = make([]models.Todo, 0)
+	}
+	return tree, nil
+}
+
+// GetAllTodos lists the todos of the personal projects a user has access to,
+// or those of a workspace when workspaceID is given.
+func GetAllTodos(userID, workspaceID string, filters models.TodoFilters) (models.TodoPage, error) {
+	return getTodoPage(userID, workspaceID, false, filters)
+}
 
-	_, crtErr := database.Todo.Exec(SQL, body.Name, body.Description, body.UserID)
-	return crtErr
+// GetAssignedTodos lists the todos assigned to a user across all projects and
+// workspaces, leaving out those the user has lost access to since.
+func GetAssignedTodos(userID string, filters models.TodoFilters) (models.TodoPage, error) {
+	return getTodoPage(userID, "", true, filters)
 }
 
-func GetAllTodos(userID, keyword, completed string) ([]models.Todo, error) {
-	SQL := `SELECT id, user_id, name, description, is_completed
+// getTodoPage runs the filters of a todo listing, either on the todos of a
+// workspace or the personal ones, or with assigned on those assigned to the
+// user.
+func getTodoPage(userID, workspaceID string, assigned bool, filters models.TodoFilters) (models.TodoPage, error) {
+	page := models.TodoPage{Items: make([]models.Todo, 0)}
+
+	sorts := filters.Sort
+	if len(sorts) == 0 {
+		sorts = defaultTodoSort
+		if filters.Query != "" {
+			sorts = defaultSearchSort
+		}
+	}
+	orderBy, orderErr := todoOrderBy(sorts)
+	if orderErr != nil {
+		return page, orderErr
+	}
+
+	assignee := filters.Assignee
+	if assignee == models.AssigneeMe {
+		assignee = userID
+	}
+
+	args := []interface{}{userID, filters.Keyword, filters.Completed,
+		filters.DueBefore, filters.DueAfter, filters.Overdue, filters.IncludeUnstarted, filters.ProjectID,
+		filters.IncludeSubtasks, filters.CompletedBefore, filters.CompletedAfter, filters.Query, workspaceID,
+		assigned, assignee}
+
+	SQL := `SELECT ` + todoColumns + `,
+				CASE WHEN $12 = '' THEN NULL ELSE ` + todoRank + ` END AS rank
 				FROM todos
-				WHERE user_id = $1
+				WHERE CASE
+					WHEN $14 THEN assignee_id = $1 AND todo_role(id, $1) IS NOT NULL
+					ELSE project_id IN (
+						SELECT id FROM projects WHERE workspace_id IS NOT DISTINCT FROM CAST(NULLIF($13, '') AS UUID)
+						)
+						AND ($13 <> '' OR user_id = $1 OR id IN (SELECT id FROM shared_todos($1)))
+					END
+				  AND ($15 = '' OR ($15 = 'none' AND assignee_id IS NULL) OR CAST(assignee_id AS TEXT) = $15)
 				  AND (
 					$2 = '' OR (name ILIKE '%' || $2 || '%' OR description ILIKE '%' || $2 || '%')
 					)
 				  AND ($3 = '' OR is_completed = CAST($3 AS BOOLEAN))
+				  AND (CAST($4 AS TIMESTAMPTZ) IS NULL OR due_at < $4)
+				  AND (CAST($5 AS TIMESTAMPTZ) IS NULL OR due_at > $5)
+				  AND (NOT $6 OR (due_at < NOW() AND NOT is_completed))
+				  AND ($7 OR start_at IS NULL OR start_at <= NOW())
+				  AND ($8 = '' OR project_id = CAST($8 AS UUID))
+				  AND ($9 OR parent_id IS NULL)
+				  AND (CAST($10 AS TIMESTAMPTZ) IS NULL OR completed_at < $10)
+				  AND (CAST($11 AS TIMESTAMPTZ) IS NULL OR completed_at > $11)
+				  AND ($12 = '' OR search_vector @@ ` + todoSearchQuery + `)
 				  AND archived_at IS NULL`
 
-	todos := make([]models.Todo, 0)
-	getErr := database.Todo.Select(&todos, SQL, userID, keyword, completed)
-	return todos, getErr
+	if len(filters.Tags) > 0 {
+		args = append(args, pq.Array(filters.Tags))
+		tagMatch := fmt.Sprintf(`SELECT count(DISTINCT t.name)
+					FROM todo_tags tt
+					JOIN tags t ON t.id = tt.tag_id
+					WHERE tt.todo_id = todos.id
+					  AND t.archived_at IS NULL
+					  AND t.name = ANY($%d)`, len(args))
+		if filters.MatchAllTags {
+			SQL += fmt.Sprintf(`
+				  AND (%s) = cardinality($%d)`, tagMatch, len(args))
+		} else {
+			SQL += fmt.Sprintf(`
+				  AND (%s) > 0`, tagMatch)
+		}
+	}
+
+	if filters.Cursor != nil {
+		condition, condErr := todoKeysetCondition(sorts, *filters.Cursor, &args)
+		if condErr != nil {
+			return page, condErr
+		}
+		SQL += `
+				  AND ` + condition
+	}
+
+	// one extra row is fetched to find out whether another page follows
+	args = append(args, filters.Limit+1)
+	SQL += fmt.Sprintf(`
+				ORDER BY %s
+				LIMIT $%d`, orderBy, len(args))
+
+	if getErr := database.Todo.Select(&page.Items, SQL, args...); getErr != nil {
+		return page, getErr
+	}
+
+	if len(page.Items) > filters.Limit {
+		page.Items = page.Items[:filters.Limit]
+		cursor, encErr := utils.EncodeCursor(newTodoCursor(sorts, page.Items[len(page.Items)-1]))
+		if encErr != nil {
+			return page, encErr
+		}
+		page.NextCursor = cursor
+	}
+
+	if filters.Query != "" {
+		if err := loadTodoHighlights(page.Items, filters.Query); err != nil {
+			return page, err
+		}
+	}
+	return page, loadTodoRelations(page.Items, userID)
 }
 
-func MarkCompleted(todoID, userID string) error {
+// loadTodoHighlights marks the words matching a full-text search query in the
+// name and description of todos. It runs on the final page only since
+// ts_headline has to re-parse the text of every row.
+func loadTodoHighlights(todos []models.Todo, query string) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	todoIDs := make([]string, 0, len(todos))
+	for i := range todos {
+		todoIDs = append(todoIDs, todos[i].ID)
+	}
+
+	SQL := `SELECT id,
+			       ts_headline('english', name, q, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>') AS name,
+			       ts_headline('english', description, q,
+			                   'MaxFragments=2, MaxWords=20, MinWords=5, StartSel=<mark>, StopSel=</mark>') AS description
+			  FROM todos, websearch_to_tsquery('english', $2) q
+			  WHERE id = ANY($1)`
+
+	var rows []struct {
+		ID string `db:"id"`
+		models.TodoHighlight
+	}
+	if getErr := database.Todo.Select(&rows, SQL, pq.Array(todoIDs), query); getErr != nil {
+		return getErr
+	}
+
+	index := make(map[string]int, len(todos))
+	for i := range todos {
+		index[todos[i].ID] = i
+	}
+	for _, row := range rows {
+		highlight := row.TodoHighlight
+		todos[index[row.ID]].Highlight = &highlight
+	}
+	return nil
+}
+
+func UpdateTodo(db sqlx.Ext, todoID, userID string, body models.UpdateTodoRequest) (models.Todo, error) {
 	SQL := `UPDATE todos
-              SET is_completed = true        
-              WHERE id = $1                  
-                AND user_id = $2             
-                AND archived_at IS NULL`
+			  SET name         = COALESCE(TRIM($3), name),
+			      description  = COALESCE(TRIM($4), description),
+			      is_completed = COALESCE($5, is_completed),
+			      completed_at = CASE WHEN $5 IS NULL THEN completed_at
+			                          WHEN $5 THEN COALESCE(completed_at, NOW())
+			                     END,
+			      due_at       = CASE WHEN $6 THEN NULL ELSE COALESCE($7, due_at) END,
+			      start_at     = CASE WHEN $8 THEN NULL ELSE COALESCE($9, start_at) END,
+			      priority     = COALESCE($10, priority),
+			      project_id   = COALESCE($11, project_id),
+			      recurrence_rule     = CASE WHEN $12 THEN NULL ELSE COALESCE($13, recurrence_rule) END,
+			      recurrence_timezone = CASE WHEN $12 THEN NULL
+			                                 ELSE COALESCE($14, recurrence_timezone, CASE WHEN $13 IS NOT NULL THEN 'UTC' END)
+			                            END,
+			      recurrence_start    = CASE WHEN $12 OR COALESCE($13, recurrence_rule) IS NULL THEN NULL
+			                                 WHEN $13 IS NOT NULL OR $7 IS NOT NULL THEN COALESCE($7, due_at)
+			                                 ELSE recurrence_start
+			                            END,
+			      recurrence_index    = CASE WHEN $13 IS NOT NULL THEN 1 ELSE recurrence_index END
+			  WHERE id = $1
+			    AND todo_role(id, $2) IN ('owner', 'editor')
+			    AND archived_at IS NULL
+			  RETURNING ` + todoColumns
+
+	var todo models.Todo
+	if err := validateUUIDs(todoID); err != nil {
+		return todo, err
+	}
+
+	updErr := sqlx.Get(db, &todo, SQL, todoID, userID, body.Name, body.Description, body.IsCompleted,
+		body.ClearDueAt, body.DueAt, body.ClearStartAt, body.StartAt, body.Priority, body.ProjectID,
+		body.ClearRecurrence, body.RecurrenceRule, body.RecurrenceTimezone)
+	if isUniqueViolation(updErr) {
+		return todo, ErrTodoAlreadyExists
+	}
+	if isWIPLimitViolation(updErr) {
+		return todo, ErrWIPLimitReached
+	}
+	if isCheckViolation(updErr, scheduleConstraint) {
+		return todo, ErrInvalidSchedule
+	}
+	if isCheckViolation(updErr, recurrenceConstraint) {
+		return todo, ErrRecurrenceDueDate
+	}
+	if updErr != nil {
+		return todo, todoMissing(db, todoID, userID, updErr)
+	}
 
-	_, updErr := database.Todo.Exec(SQL, todoID, userID)
+	todos := []models.Todo{todo}
+	loadErr := loadTodoRelations(todos, userID)
+	return todos[0], loadErr
+}
+
+// MoveSubtasks moves every live descendant of a todo into the given project so
+// that a subtree never spans more than one project.
+func MoveSubtasks(db sqlx.Ext, todoID, projectID string) error {
+	SQL := `WITH RECURSIVE tree AS (
+				SELECT id
+				  FROM todos
+				  WHERE parent_id = $1
+				    AND archived_at IS NULL
+				UNION ALL
+				SELECT t.id
+				  FROM todos t
+				  JOIN tree ON t.parent_id = tree.id
+				  WHERE t.archived_at IS NULL
+			)
+			UPDATE todos
+			  SET project_id = $2
+			  WHERE id IN (SELECT id FROM tree)`
+
+	_, updErr := db.Exec(SQL, todoID, projectID)
+	if isWIPLimitViolation(updErr) {
+		return ErrWIPLimitReached
+	}
 	return updErr
 }
 
-func DeleteTodo(todoID, userID string) error {
+// MarkCompleted completes a todo and, when cascade is set, all of its live
+// descendants as well.
+func MarkCompleted(db sqlx.Ext, todoID, userID string, cascade bool) error {
+	SQL := `WITH RECURSIVE tree AS (
+				SELECT id
+				  FROM todos
+				  WHERE id = $1
+				    AND todo_role(id, $2) IN ('owner', 'editor')
+				    AND archived_at IS NULL
+				UNION ALL
+				SELECT t.id
+				  FROM todos t
+				  JOIN tree ON t.parent_id = tree.id
+				  WHERE $3
+				    AND t.archived_at IS NULL
+			)
+			UPDATE todos
+              SET is_completed = true,
+                  completed_at = COALESCE(completed_at, NOW())
+              WHERE id IN (SELECT id FROM tree)`
+
+	if err := validateUUIDs(todoID); err != nil {
+		return err
+	}
+
+	updErr := expectAffected(db.Exec(SQL, todoID, userID, cascade))
+	if isWIPLimitViolation(updErr) {
+		return ErrWIPLimitReached
+	}
+	return todoMissing(db, todoID, userID, updErr)
+}
+
+func MarkIncomplete(db sqlx.Ext, todoID, userID string) error {
 	SQL := `UPDATE todos
-			  SET archived_at = NOW()        
-			  WHERE id = $1                  
-			    AND user_id = $2             
+			  SET is_completed = false,
+			      completed_at = NULL
+			  WHERE id = $1
+			    AND todo_role(id, $2) IN ('owner', 'editor')
 			    AND archived_at IS NULL`
 
-	_, delErr := database.Todo.Exec(SQL, todoID, userID)
-	return delErr
+	if err := validateUUIDs(todoID); err != nil {
+		return err
+	}
+
+	updErr := expectAffected(db.Exec(SQL, todoID, userID))
+	if isUniqueViolation(updErr) {
+		return ErrTodoAlreadyExists
+	}
+	if isWIPLimitViolation(updErr) {
+		return ErrWIPLimitReached
+	}
+	return todoMissing(db, todoID, userID, updErr)
 }
 
-func DeleteAllTodos(userID string) error {
+// AssignTodo assigns a todo locked by LockTodo to a user with access to it, or
+// unassigns it when assigneeID is nil.
+func AssignTodo(db sqlx.Ext, todoID string, assigneeID *string) error {
+	if assigneeID != nil {
+		if err := validateUUIDs(*assigneeID); err != nil {
+			return err
+		}
+
+		SQL := `SELECT todo_role($1, id) IS NOT NULL
+				  FROM users
+				  WHERE id = $2
+				    AND archived_at IS NULL`
+
+		var access bool
+		if chkErr := sqlx.Get(db, &access, SQL, todoID, *assigneeID); chkErr != nil && !errors.Is(chkErr, sql.ErrNoRows) {
+			return chkErr
+		}
+		if !access {
+			return ErrAssigneeNoAccess
+		}
+	}
+
+	SQL := `UPDATE todos
+			  SET assignee_id = $2
+			  WHERE id = $1`
+
+	_, updErr := db.Exec(SQL, todoID, assigneeID)
+	return updErr
+}
+
+// DeleteTodo archives a todo together with all of its subtasks. Only the
+// owner may delete a todo, also when it is shared.
+func DeleteTodo(db sqlx.Ext, todoID, userID string) error {
+	SQL := `WITH RECURSIVE tree AS (
+				SELECT id
+				  FROM todos
+				  WHERE id = $1
+				    AND user_id = $2
+				    AND archived_at IS NULL
+				UNION ALL
+				SELECT t.id
+				  FROM todos t
+				  JOIN tree ON t.parent_id = tree.id
+				  WHERE t.archived_at IS NULL
+			)
+			UPDATE todos
+			  SET archived_at = NOW()
+			  WHERE id IN (SELECT id FROM tree)`
+
+	if err := validateUUIDs(todoID); err != nil {
+		return err
+	}
+
+	delErr := expectAffected(db.Exec(SQL, todoID, userID))
+	return todoMissing(db, todoID, userID, delErr)
+}
+
+// GetTrashedTodos lists the archived todos of a user that can be restored on
+// their own, i.e. leaving out subtasks that were archived with their parent.
+func GetTrashedTodos(userID string) ([]models.Todo, error) {
+	SQL := `SELECT ` + todoColumns + `
+			  FROM todos
+			  WHERE user_id = $1
+			    AND archived_at IS NOT NULL
+			    AND NOT EXISTS (SELECT 1
+			                      FROM todos p
+			                      WHERE p.id = todos.parent_id
+			                        AND p.archived_at IS NOT NULL)
+			  ORDER BY archived_at DESC, id`
+
+	todos := make([]models.Todo, 0)
+	if getErr := database.Todo.Select(&todos, SQL, userID); getErr != nil {
+		return nil, getErr
+	}
+	return todos, loadTodoRelations(todos, userID)
+}
+
+func LockTrashedTodo(db sqlx.Ext, todoID, userID string) (models.Todo, error) {
+	SQL := `SELECT ` + todoColumns + `
+			  FROM todos
+			  WHERE id = $1
+			    AND user_id = $2
+			    AND archived_at IS NOT NULL
+			  FOR UPDATE`
+
+	var todo models.Todo
+	if err := validateUUIDs(todoID); err != nil {
+		return todo, err
+	}
+
+	getErr := sqlx.Get(db, &todo, SQL, todoID, userID)
+	return todo, notFound(getErr)
+}
+
+// RestoreTodo brings an archived todo back under the given name and project,
+// together with the subtasks that were archived along with it.
+func RestoreTodo(db sqlx.Ext, todoID, userID, name, projectID string) error {
+	SQL := `WITH RECURSIVE tree AS (
+				SELECT id, archived_at
+				  FROM todos
+				  WHERE id = $1
+				    AND user_id = $2
+				    AND archived_at IS NOT NULL
+				UNION ALL
+				SELECT t.id, t.archived_at
+				  FROM todos t
+				  JOIN tree ON t.parent_id = tree.id
+				  WHERE t.archived_at = tree.archived_at
+			)
+			UPDATE todos
+			  SET archived_at = NULL,
+			      project_id  = $4,
+			      name        = CASE WHEN id = $1 THEN TRIM($3) ELSE name END
+			  WHERE id IN (SELECT id FROM tree)`
+
+	updErr := expectAffected(db.Exec(SQL, todoID, userID, name, projectID))
+	if isUniqueViolation(updErr) {
+		return ErrTodoAlreadyExists
+	}
+	if isWIPLimitViolation(updErr) {
+		return ErrWIPLimitReached
+	}
+	return updErr
+}
+
+// PurgeTodo permanently deletes an archived todo, its whole subtree and
+// everything attached to them.
+func PurgeTodo(db sqlx.Ext, todoID, userID string) error {
+	SQL := `WITH RECURSIVE tree AS (
+				SELECT id
+				  FROM todos
+				  WHERE id = $1
+				    AND user_id = $2
+				    AND archived_at IS NOT NULL
+				UNION ALL
+				SELECT t.id
+				  FROM todos t
+				  JOIN tree ON t.parent_id = tree.id
+			), tags AS (
+				DELETE FROM todo_tags
+				  WHERE todo_id IN (SELECT id FROM tree)
+			), dependencies AS (
+				DELETE FROM todo_dependencies
+				  WHERE todo_id IN (SELECT id FROM tree)
+				     OR blocker_id IN (SELECT id FROM tree)
+			), comments AS (
+				DELETE FROM todo_comments
+				  WHERE todo_id IN (SELECT id FROM tree)
+			), attachments AS (
+				DELETE FROM todo_attachments
+				  WHERE todo_id IN (SELECT id FROM tree)
+				  RETURNING storage_key
+			), blobs AS (
+				INSERT INTO blob_deletions (storage_key)
+				  SELECT storage_key
+				    FROM attachments
+				  ON CONFLICT DO NOTHING
+			), shared AS (
+				DELETE FROM shares
+				  WHERE todo_id IN (SELECT id FROM tree)
+			)
+			DELETE FROM todos
+			  WHERE id IN (SELECT id FROM tree)`
+
+	if err := validateUUIDs(todoID); err != nil {
+		return err
+	}
+
+	return expectAffected(db.Exec(SQL, todoID, userID))
+}
+
+func DeleteAllTodos(db sqlx.Ext, userID string) error {
 	SQL := `UPDATE todos
               SET archived_at = NOW()        
               WHERE user_id = $1             
                 AND archived_at IS NULL`
 
-	_, delErr := database.Todo.Exec(SQL, userID)
+	_, delErr := db.Exec(SQL, userID)
 	return delErr
 }
+
+// todoOrderBy builds the ORDER BY clause for the given sort keys. Only columns
+// from todoSortColumns ever reach the query; id is always appended as a final
+// tiebreaker so the order is deterministic and usable for keyset pagination.
+func todoOrderBy(sorts []models.TodoSort) (string, error) {
+	clauses := make([]string, 0, len(sorts)+1)
+	for _, sort := range sorts {
+		column, ok := todoSortColumns[sort.Key]
+		if !ok {
+			return "", fmt.Errorf("unsupported sort key %q", sort.Key)
+		}
+		direction := "ASC"
+		if sort.Desc {
+			direction = "DESC"
+		}
+		clauses = append(clauses, column.column+" "+direction+" NULLS LAST")
+	}
+	clauses = append(clauses, "id ASC")
+	return strings.Join(clauses, ", "), nil
+}
+
+func todoSortSignature(sorts []models.TodoSort) string {
+	keys := make([]string, 0, len(sorts))
+	for _, sort := range sorts {
+		key := string(sort.Key)
+		if sort.Desc {
+			key += ":desc"
+		}
+		keys = append(keys, key)
+	}
+	return strings.Join(keys, ",")
+}
+
+func newTodoCursor(sorts []models.TodoSort, last models.Todo) models.TodoCursor {
+	cursor := models.TodoCursor{
+		Sort:   todoSortSignature(sorts),
+		Values: make([]*string, 0, len(sorts)),
+		ID:     last.ID,
+	}
+	for _, sort := range sorts {
+		cursor.Values = append(cursor.Values, todoSortColumns[sort.Key].value(last))
+	}
+	return cursor
+}
+
+// todoKeysetCondition returns a predicate matching the rows that sort strictly
+// after the cursor, appending its placeholders' values to args. NULLs always
+// sort last, whatever the direction, which mirrors todoOrderBy.
+func todoKeysetCondition(sorts []models.TodoSort, cursor models.TodoCursor, args *[]interface{}) (string, error) {
+	if cursor.Sort != todoSortSignature(sorts) || len(cursor.Values) != len(sorts) || !utils.IsUUID(cursor.ID) {
+		return "", ErrCursorMismatch
+	}
+
+	placeholder := func(value interface{}) string {
+		*args = append(*args, value)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+
+	alternatives := make([]string, 0, len(sorts)+1)
+	equalities := make([]string, 0, len(sorts))
+	for i, sort := range sorts {
+		column := todoSortColumns[sort.Key]
+		value := cursor.Values[i]
+
+		if value != nil {
+			operator := ">"
+			if sort.Desc {
+				operator = "<"
+			}
+			bound := fmt.Sprintf("CAST(%s AS %s)", placeholder(*value), column.cast)
+			after := fmt.Sprintf("(%s %s %s OR %s IS NULL)", column.column, operator, bound, column.column)
+			alternatives = append(alternatives, joinConditions(append(equalities, after)))
+			equalities = append(equalities, fmt.Sprintf("%s = %s", column.column, bound))
+		} else {
+			equalities = append(equalities, column.column+" IS NULL")
+		}
+	}
+	after := fmt.Sprintf("id > CAST(%s AS UUID)", placeholder(cursor.ID))
+	alternatives = append(alternatives, joinConditions(append(equalities, after)))
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", nil
+}
+
+// loadTodoRelations fills in the tags and dependencies of every todo as far
+// as the user may see them.
+func loadTodoRelations(todos []models.Todo, userID string) error {
+	if err := loadTodoTags(todos); err != nil {
+		return err
+	}
+	return loadTodoDependencies(todos, userID)
+}
+
+func joinConditions(conditions []string) string {
+	return "(" + strings.Join(conditions, " AND ") + ")"
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func timePtrString(value *time.Time) *string {
+	if value == nil {
+		return nil
+	}
+	return stringPtr(value.Format(time.RFC3339Nano))
+}
@@ -4,6 +4,7 @@ import (
 	"Todo/database"
 	"Todo/models"
 	"Todo/utils"
+	"github.com/jmoiron/sqlx"
 	"time"
 )
 
@@ -18,19 +19,21 @@ func IsUserExists(email string) (bool, error) {
 	return check, chkErr
 }
 
-func CreateUser(name, email, password string) error {
+func CreateUser(db sqlx.Ext, name, email, password string) (string, error) {
 	SQL := `INSERT INTO users (name, email, password)
-			  VALUES (TRIM($1), TRIM($2), $3)`
+			  VALUES (TRIM($1), TRIM($2), $3)
+			  RETURNING id`
 
-	_, crtErr := database.Todo.Exec(SQL, name, email, password)
-	return crtErr
+	var userID string
+	crtErr := sqlx.Get(db, &userID, SQL, name, email, password)
+	return userID, crtErr
 }
 
-func CreateUserSession(userID string) (string, error) {
+func CreateUserSession(db sqlx.Ext, userID string) (string, error) {
 	var sessionID string
 	SQL := `INSERT INTO user_session(user_id) 
               VALUES ($1) RETURNING id`
-	crtErr := database.Todo.Get(&sessionID, SQL, userID)
+	crtErr := sqlx.Get(db, &sessionID, SQL, userID)
 	return sessionID, crtErr
 }
 
@@ -73,22 +76,22 @@ func GetArchivedAt(sessionID string) (*time.Time, error) {
 	return archivedAt, getErr
 }
 
-func DeleteUserSession(sessionID string) error {
+func DeleteUserSession(db sqlx.Ext, sessionID string) error {
 	SQL := `UPDATE user_session
 			  SET archived_at = NOW()
 			  WHERE id = $1
 			    AND archived_at IS NULL`
 
-	_, delErr := database.Todo.Exec(SQL, sessionID)
+	_, delErr := db.Exec(SQL, sessionID)
 	return delErr
 }
 
-func DeleteUser(userID string) error {
+func DeleteUser(db sqlx.Ext, userID string) error {
 	SQL := `UPDATE users
 			  SET archived_at = NOW()
 			  WHERE id = $1
 			    AND archived_at IS NULL`
 
-	_, delErr := database.Todo.Exec(SQL, userID)
+	_, delErr := db.Exec(SQL, userID)
 	return delErr
 }
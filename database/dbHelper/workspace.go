@@ -0,0 +1,380 @@
+package dbHelper
+
+import (
+	"Todo/database"
+	"Todo/models"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+const invitationColumns = `id, workspace_id, email, role, invited_by, created_at, expires_at`
+
+const memberColumns = `m.workspace_id, m.user_id, u.name, u.email, m.role, m.created_at`
+
+// CreateWorkspace creates a workspace with userID as its owner.
+func CreateWorkspace(db sqlx.Ext, userID, name string) (models.Workspace, error) {
+	SQL := `WITH workspace AS (
+				INSERT INTO workspaces (name, created_by)
+				VALUES (TRIM($2), $1)
+				RETURNING id, name, created_at
+			  ), owner AS (
+				INSERT INTO workspace_members (workspace_id, user_id, role)
+				SELECT id, $1, CAST('owner' AS workspace_role)
+				  FROM workspace
+			  )
+			  SELECT id, name, 'owner' AS role, created_at
+			    FROM workspace`
+
+	var workspace models.Workspace
+	crtErr := sqlx.Get(db, &workspace, SQL, userID, name)
+	return workspace, crtErr
+}
+
+// GetWorkspaces lists the workspaces a user is a member of along with their
+// role in each.
+func GetWorkspaces(userID string) ([]models.Workspace, error) {
+	SQL := `SELECT w.id, w.name, m.role, w.created_at
+			  FROM workspace_members m
+			  JOIN workspaces w ON w.id = m.workspace_id
+			  WHERE m.user_id = $1
+			    AND m.archived_at IS NULL
+			    AND w.archived_at IS NULL
+			  ORDER BY w.name`
+
+	workspaces := make([]models.Workspace, 0)
+	getErr := database.Todo.Select(&workspaces, SQL, userID)
+	return workspaces, getErr
+}
+
+func GetWorkspace(workspaceID, userID string) (models.Workspace, error) {
+	SQL := `SELECT id, name, workspace_role(id, $2) AS role, created_at
+			  FROM workspaces
+			  WHERE id = $1
+			    AND archived_at IS NULL
+			    AND workspace_role(id, $2) IS NOT NULL`
+
+	var workspace models.Workspace
+	if err := validateUUIDs(workspaceID); err != nil {
+		return workspace, err
+	}
+
+	getErr := database.Todo.Get(&workspace, SQL, workspaceID, userID)
+	return workspace, notFound(getErr)
+}
+
+// GetWorkspaceRole returns the role of a user in a live workspace. Users who
+// are not members get ErrNotFound.
+func GetWorkspaceRole(workspaceID, userID string) (models.WorkspaceRole, error) {
+	SQL := `SELECT workspace_role($1, $2)`
+
+	if err := validateUUIDs(workspaceID); err != nil {
+		return "", err
+	}
+
+	var role *models.WorkspaceRole
+	if getErr := database.Todo.Get(&role, SQL, workspaceID, userID); getErr != nil {
+		return "", getErr
+	}
+	if role == nil {
+		return "", ErrNotFound
+	}
+	return *role, nil
+}
+
+// LockWorkspace returns a live workspace of a member and locks it until the
+// end of the transaction, so that a change can be logged with the workspace
+// before it.
+func LockWorkspace(db sqlx.Ext, workspaceID, userID string) (models.Workspace, error) {
+	SQL := `SELECT id, name, workspace_role(id, $2) AS role, created_at
+			  FROM workspaces
+			  WHERE id = $1
+			    AND archived_at IS NULL
+			    AND workspace_role(id, $2) IS NOT NULL
+			  FOR UPDATE`
+
+	var workspace models.Workspace
+	if err := validateUUIDs(workspaceID); err != nil {
+		return workspace, err
+	}
+
+	getErr := sqlx.Get(db, &workspace, SQL, workspaceID, userID)
+	return workspace, notFound(getErr)
+}
+
+func RenameWorkspace(db sqlx.Ext, workspaceID, userID, name string) (models.Workspace, error) {
+	SQL := `UPDATE workspaces
+			  SET name = TRIM($3)
+			  WHERE id = $1
+			    AND archived_at IS NULL
+			    AND workspace_role(id, $2) IN ('owner', 'admin')
+			  RETURNING id, name, workspace_role(id, $2) AS role, created_at`
+
+	var workspace models.Workspace
+	if err := validateUUIDs(workspaceID); err != nil {
+		return workspace, err
+	}
+
+	updErr := sqlx.Get(db, &workspace, SQL, workspaceID, userID, name)
+	if updErr != nil {
+		return workspace, workspaceMissing(workspaceID, userID, notFound(updErr))
+	}
+	return workspace, nil
+}
+
+// DeleteWorkspace archives a workspace along with its memberships, pending
+// invitations, projects and their todos and returns it. Only the owner may
+// delete it.
+func DeleteWorkspace(db sqlx.Ext, workspaceID, userID string) (models.Workspace, error) {
+	SQL := `UPDATE workspaces
+			  SET archived_at = NOW()
+			  WHERE id = $1
+			    AND archived_at IS NULL
+			    AND workspace_role(id, $2) = 'owner'
+			  RETURNING id, name, workspace_role(id, $2) AS role, created_at`
+
+	var workspace models.Workspace
+	if err := validateUUIDs(workspaceID); err != nil {
+		return workspace, err
+	}
+
+	if delErr := sqlx.Get(db, &workspace, SQL, workspaceID, userID); delErr != nil {
+		return workspace, workspaceMissing(workspaceID, userID, notFound(delErr))
+	}
+
+	SQL = `WITH archived_projects AS (
+				UPDATE projects
+				SET archived_at = NOW()
+				WHERE workspace_id = $1
+				  AND archived_at IS NULL
+				RETURNING id
+			 ), archived_todos AS (
+				UPDATE todos
+				SET archived_at = NOW()
+				WHERE project_id IN (SELECT id FROM archived_projects)
+				  AND archived_at IS NULL
+			 ), members AS (
+				UPDATE workspace_members
+				SET archived_at = NOW()
+				WHERE workspace_id = $1
+				  AND archived_at IS NULL
+			 )
+			 UPDATE workspace_invitations
+			 SET archived_at = NOW()
+			 WHERE workspace_id = $1
+			   AND accepted_at IS NULL
+			   AND archived_at IS NULL`
+
+	_, delErr := db.Exec(SQL, workspaceID)
+	return workspace, delErr
+}
+
+func GetWorkspaceMembers(workspaceID string) ([]models.WorkspaceMember, error) {
+	SQL := `SELECT ` + memberColumns + `
+			  FROM workspace_members m
+			  JOIN users u ON u.id = m.user_id
+			  WHERE m.workspace_id = $1
+			    AND m.archived_at IS NULL
+			  ORDER BY m.role DESC, u.name`
+
+	members := make([]models.WorkspaceMember, 0)
+	getErr := database.Todo.Select(&members, SQL, workspaceID)
+	return members, getErr
+}
+
+// LockWorkspaceMember returns a member of a workspace and keeps their
+// membership locked until the surrounding transaction ends.
+func LockWorkspaceMember(db sqlx.Ext, workspaceID, memberID string) (models.WorkspaceMember, error) {
+	SQL := `SELECT ` + memberColumns + `
+			  FROM workspace_members m
+			  JOIN users u ON u.id = m.user_id
+			  WHERE m.workspace_id = $1
+			    AND m.user_id = $2
+			    AND m.archived_at IS NULL
+			  FOR UPDATE OF m`
+
+	var member models.WorkspaceMember
+	if err := validateUUIDs(memberID); err != nil {
+		return member, err
+	}
+
+	getErr := sqlx.Get(db, &member, SQL, workspaceID, memberID)
+	if errors.Is(getErr, sql.ErrNoRows) {
+		return member, ErrMemberNotFound
+	}
+	return member, getErr
+}
+
+func UpdateWorkspaceMember(db sqlx.Ext, workspaceID, memberID string, role models.WorkspaceRole) (models.WorkspaceMember, error) {
+	SQL := `UPDATE workspace_members m
+			  SET role = $3
+			  FROM users u
+			  WHERE u.id = m.user_id
+			    AND m.workspace_id = $1
+			    AND m.user_id = $2
+			    AND m.archived_at IS NULL
+			  RETURNING ` + memberColumns
+
+	var member models.WorkspaceMember
+	updErr := sqlx.Get(db, &member, SQL, workspaceID, memberID, role)
+	if errors.Is(updErr, sql.ErrNoRows) {
+		return member, ErrMemberNotFound
+	}
+	return member, updErr
+}
+
+func RemoveWorkspaceMember(db sqlx.Ext, workspaceID, memberID string) error {
+	SQL := `UPDATE workspace_members
+			  SET archived_at = NOW()
+			  WHERE workspace_id = $1
+			    AND user_id = $2
+			    AND archived_at IS NULL`
+
+	delErr := expectAffected(db.Exec(SQL, workspaceID, memberID))
+	if errors.Is(delErr, ErrNotFound) {
+		return ErrMemberNotFound
+	}
+	return delErr
+}
+
+// CreateInvitation invites an email address into a workspace. A pending
+// invitation for the same address is replaced, so inviting again sends a
+// fresh token.
+func CreateInvitation(db sqlx.Ext, workspaceID, invitedBy, tokenHash string, expiresAt time.Time, body models.InvitationRequest) (models.Invitation, error) {
+	var invitation models.Invitation
+
+	SQL := `SELECT count(m.id) > 0 AS is_member
+			  FROM workspace_members m
+			  JOIN users u ON u.id = m.user_id
+			  WHERE m.workspace_id = $1
+			    AND u.email = TRIM($2)
+			    AND m.archived_at IS NULL
+			    AND u.archived_at IS NULL`
+
+	var member bool
+	if chkErr := sqlx.Get(db, &member, SQL, workspaceID, body.Email); chkErr != nil {
+		return invitation, chkErr
+	}
+	if member {
+		return invitation, ErrAlreadyMember
+	}
+
+	SQL = `UPDATE workspace_invitations
+			 SET archived_at = NOW()
+			 WHERE workspace_id = $1
+			   AND email = TRIM($2)
+			   AND accepted_at IS NULL
+			   AND archived_at IS NULL`
+
+	if _, delErr := db.Exec(SQL, workspaceID, body.Email); delErr != nil {
+		return invitation, delErr
+	}
+
+	SQL = `INSERT INTO workspace_invitations (workspace_id, email, role, token_hash, invited_by, expires_at)
+			 VALUES ($1, TRIM($2), $3, $4, $5, $6)
+			 RETURNING ` + invitationColumns
+
+	crtErr := sqlx.Get(db, &invitation, SQL, workspaceID, body.Email, body.Role, tokenHash, invitedBy, expiresAt)
+	if isUniqueViolation(crtErr) {
+		return invitation, ErrAlreadyInvited
+	}
+	return invitation, crtErr
+}
+
+// GetInvitations lists the invitations of a workspace that can still be
+// accepted.
+func GetInvitations(workspaceID string) ([]models.Invitation, error) {
+	SQL := `SELECT ` + invitationColumns + `
+			  FROM workspace_invitations
+			  WHERE workspace_id = $1
+			    AND accepted_at IS NULL
+			    AND archived_at IS NULL
+			    AND expires_at > NOW()
+			  ORDER BY created_at DESC`
+
+	invitations := make([]models.Invitation, 0)
+	getErr := database.Todo.Select(&invitations, SQL, workspaceID)
+	return invitations, getErr
+}
+
+// RevokeInvitation archives a pending invitation and returns it.
+func RevokeInvitation(db sqlx.Ext, workspaceID, invitationID string) (models.Invitation, error) {
+	SQL := `UPDATE workspace_invitations
+			  SET archived_at = NOW()
+			  WHERE id = $1
+			    AND workspace_id = $2
+			    AND accepted_at IS NULL
+			    AND archived_at IS NULL
+			  RETURNING ` + invitationColumns
+
+	var invitation models.Invitation
+	if err := validateUUIDs(invitationID); err != nil {
+		return invitation, err
+	}
+
+	delErr := sqlx.Get(db, &invitation, SQL, invitationID, workspaceID)
+	if errors.Is(delErr, sql.ErrNoRows) {
+		return invitation, ErrInvitationNotFound
+	}
+	return invitation, delErr
+}
+
+// AcceptInvitation adds a user to the workspace of a pending invitation sent
+// to their email address and returns the workspace. Expired, revoked and
+// unknown tokens as well as invitations for another address all report
+// ErrInvitationNotFound.
+func AcceptInvitation(db sqlx.Ext, tokenHash, userID string) (models.Workspace, error) {
+	SQL := `UPDATE workspace_invitations i
+			  SET accepted_at = NOW()
+			  FROM users u, workspaces w
+			  WHERE i.token_hash = $1
+			    AND u.id = $2
+			    AND i.email = u.email
+			    AND w.id = i.workspace_id
+			    AND w.archived_at IS NULL
+			    AND i.accepted_at IS NULL
+			    AND i.archived_at IS NULL
+			    AND i.expires_at > NOW()
+			  RETURNING i.workspace_id, i.role`
+
+	var workspace models.Workspace
+	var invitation struct {
+		WorkspaceID string               `db:"workspace_id"`
+		Role        models.WorkspaceRole `db:"role"`
+	}
+	updErr := sqlx.Get(db, &invitation, SQL, tokenHash, userID)
+	if errors.Is(updErr, sql.ErrNoRows) {
+		return workspace, ErrInvitationNotFound
+	}
+	if updErr != nil {
+		return workspace, updErr
+	}
+
+	SQL = `WITH member AS (
+				INSERT INTO workspace_members (workspace_id, user_id, role)
+				VALUES ($1, $2, $3)
+			 )
+			 SELECT id, name, CAST($3 AS workspace_role) AS role, created_at
+			   FROM workspaces
+			   WHERE id = $1`
+
+	crtErr := sqlx.Get(db, &workspace, SQL, invitation.WorkspaceID, userID, invitation.Role)
+	if isUniqueViolation(crtErr) {
+		return workspace, ErrAlreadyMember
+	}
+	return workspace, crtErr
+}
+
+// workspaceMissing tells members who may not change a workspace apart from
+// users who are not members at all.
+func workspaceMissing(workspaceID, userID string, err error) error {
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if _, roleErr := GetWorkspaceRole(workspaceID, userID); roleErr != nil {
+		return roleErr
+	}
+	return ErrForbidden
+}
@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS due_at   TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS start_at TIMESTAMP WITH TIME ZONE,
+    ADD CONSTRAINT todo_schedule CHECK (start_at IS NULL OR due_at IS NULL OR start_at <= due_at);
+
+CREATE INDEX IF NOT EXISTS todos_due_at ON todos (user_id, due_at) WHERE archived_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+CREATE TYPE todo_priority AS ENUM ('none', 'low', 'medium', 'high', 'urgent');
+
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS priority todo_priority NOT NULL DEFAULT 'none';
+
+COMMIT;
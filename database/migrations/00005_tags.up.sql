@@ -0,0 +1,22 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS tags
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    user_id     UUID REFERENCES users (id) NOT NULL,
+    name        TEXT                       NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS unique_tag ON tags (user_id, name) WHERE archived_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS todo_tags
+(
+    todo_id    UUID REFERENCES todos (id) NOT NULL,
+    tag_id     UUID REFERENCES tags (id)  NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (todo_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS todo_tags_tag_id ON todo_tags (tag_id);
+
+COMMIT;
@@ -0,0 +1,34 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS projects
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    user_id     UUID REFERENCES users (id) NOT NULL,
+    name        TEXT                       NOT NULL,
+    is_inbox    BOOLEAN                    NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS unique_project ON projects (user_id, name) WHERE archived_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_inbox ON projects (user_id) WHERE is_inbox AND archived_at IS NULL;
+
+INSERT INTO projects (user_id, name, is_inbox)
+SELECT id, 'Inbox', TRUE
+FROM users;
+
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS project_id UUID REFERENCES projects (id);
+
+UPDATE todos t
+SET project_id = p.id
+FROM projects p
+WHERE p.user_id = t.user_id
+  AND p.is_inbox;
+
+ALTER TABLE todos
+    ALTER COLUMN project_id SET NOT NULL;
+
+DROP INDEX IF EXISTS unique_todo;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_todo ON todos (project_id, name) WHERE archived_at IS NULL;
+
+COMMIT;
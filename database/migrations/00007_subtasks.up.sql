@@ -0,0 +1,12 @@
+BEGIN;
+
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES todos (id);
+
+CREATE INDEX IF NOT EXISTS todos_parent_id ON todos (parent_id) WHERE archived_at IS NULL;
+
+DROP INDEX IF EXISTS unique_todo;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_todo ON todos (project_id, name) WHERE parent_id IS NULL AND archived_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_subtask ON todos (parent_id, name) WHERE parent_id IS NOT NULL AND archived_at IS NULL;
+
+COMMIT;
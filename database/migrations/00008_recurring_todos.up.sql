@@ -0,0 +1,21 @@
+BEGIN;
+
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS recurrence_rule     TEXT,
+    ADD COLUMN IF NOT EXISTS recurrence_timezone TEXT,
+    ADD COLUMN IF NOT EXISTS recurrence_start    TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS recurrence_index    INTEGER NOT NULL DEFAULT 1,
+    ADD CONSTRAINT todo_recurrence CHECK (recurrence_rule IS NULL OR
+                                          (due_at IS NOT NULL AND recurrence_timezone IS NOT NULL AND
+                                           recurrence_start IS NOT NULL));
+
+-- completed occurrences of a recurring todo keep their name, so only the open
+-- occurrence takes part in the uniqueness checks
+DROP INDEX IF EXISTS unique_todo;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_todo ON todos (project_id, name)
+    WHERE parent_id IS NULL AND archived_at IS NULL AND (recurrence_rule IS NULL OR NOT is_completed);
+DROP INDEX IF EXISTS unique_subtask;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_subtask ON todos (parent_id, name)
+    WHERE parent_id IS NOT NULL AND archived_at IS NULL AND (recurrence_rule IS NULL OR NOT is_completed);
+
+COMMIT;
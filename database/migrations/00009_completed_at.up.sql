@@ -0,0 +1,14 @@
+BEGIN;
+
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP WITH TIME ZONE;
+
+-- todos completed before completion times were kept get the earliest time
+-- they can have been completed at, so that they still show up as completed
+-- in reports and completion filters
+UPDATE todos
+SET completed_at = COALESCE(created_at, NOW())
+WHERE is_completed
+  AND completed_at IS NULL;
+
+COMMIT;
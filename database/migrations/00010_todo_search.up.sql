@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+        ) STORED;
+CREATE INDEX IF NOT EXISTS todos_search_vector ON todos USING GIN (search_vector);
+
+COMMIT;
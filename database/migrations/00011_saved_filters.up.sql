@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS saved_filters
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    user_id     UUID REFERENCES users (id) NOT NULL,
+    name        TEXT                       NOT NULL,
+    criteria    JSONB                      NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS unique_saved_filter ON saved_filters (user_id, name) WHERE archived_at IS NULL;
+
+COMMIT;
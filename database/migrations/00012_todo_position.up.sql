@@ -0,0 +1,20 @@
+BEGIN;
+
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS position TEXT COLLATE "C";
+
+-- existing todos keep their creation order, using keys of the position
+-- package: base-62 digits without a trailing zero
+UPDATE todos
+SET position = ordered.position
+FROM (SELECT id,
+             lpad(CAST(row_number() OVER (PARTITION BY user_id ORDER BY created_at, id) AS TEXT), 10, '0') ||
+             'V' AS position
+      FROM todos) ordered
+WHERE todos.id = ordered.id;
+
+ALTER TABLE todos
+    ALTER COLUMN position SET NOT NULL;
+CREATE INDEX IF NOT EXISTS todos_user_position ON todos (user_id, position) WHERE archived_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS todo_dependencies
+(
+    todo_id    UUID REFERENCES todos (id) NOT NULL,
+    blocker_id UUID REFERENCES todos (id) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (todo_id, blocker_id),
+    CONSTRAINT todo_dependency_self CHECK (todo_id <> blocker_id)
+);
+CREATE INDEX IF NOT EXISTS todo_dependencies_blocker_id ON todo_dependencies (blocker_id);
+
+COMMIT;
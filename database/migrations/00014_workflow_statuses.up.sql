@@ -0,0 +1,104 @@
+BEGIN;
+
+CREATE TYPE status_category AS ENUM ('todo', 'doing', 'done');
+
+-- statuses without a project are the user's default workflow; a project that
+-- has statuses of its own uses only those
+CREATE TABLE IF NOT EXISTS statuses
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    user_id     UUID REFERENCES users (id) NOT NULL,
+    project_id  UUID REFERENCES projects (id),
+    name        TEXT                       NOT NULL,
+    category    status_category            NOT NULL,
+    position    INTEGER                    NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS unique_status
+    ON statuses (user_id, COALESCE(project_id, '00000000-0000-0000-0000-000000000000'), name)
+    WHERE archived_at IS NULL;
+
+INSERT INTO statuses (user_id, name, category, position)
+SELECT users.id, defaults.name, CAST(defaults.category AS status_category), defaults.position
+FROM users,
+     (VALUES ('To do', 'todo', 1), ('In progress', 'doing', 2), ('Done', 'done', 3)) AS defaults (name, category, position);
+
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS status_id UUID REFERENCES statuses (id);
+
+UPDATE todos
+SET status_id = s.id
+FROM statuses s
+WHERE s.user_id = todos.user_id
+  AND s.project_id IS NULL
+  AND s.category = CASE WHEN todos.is_completed THEN CAST('done' AS status_category) ELSE 'todo' END;
+
+ALTER TABLE todos
+    ALTER COLUMN status_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS todos_status_id ON todos (status_id);
+
+-- workflow_project returns the project whose statuses a todo in project uses,
+-- or NULL when it uses the user's default workflow
+CREATE OR REPLACE FUNCTION workflow_project(owner UUID, project UUID) RETURNS UUID AS
+$$
+SELECT project_id
+FROM statuses
+WHERE user_id = owner
+  AND project_id = project
+  AND archived_at IS NULL
+LIMIT 1
+$$ LANGUAGE SQL STABLE;
+
+-- todo_status returns the first status of a category in the workflow used by
+-- a project
+CREATE OR REPLACE FUNCTION todo_status(owner UUID, project UUID, wanted status_category) RETURNS UUID AS
+$$
+SELECT id
+FROM statuses
+WHERE user_id = owner
+  AND project_id IS NOT DISTINCT FROM workflow_project(owner, project)
+  AND category = wanted
+  AND archived_at IS NULL
+ORDER BY position
+LIMIT 1
+$$ LANGUAGE SQL STABLE;
+
+-- is_completed is derived from the category of the status. Writes that only
+-- touch is_completed pick the first status of the matching category, and a
+-- todo moved into a project with another workflow keeps its category.
+CREATE OR REPLACE FUNCTION sync_todo_status() RETURNS TRIGGER AS
+$$
+DECLARE
+    current_status statuses%ROWTYPE;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        IF NEW.status_id IS NULL THEN
+            NEW.status_id := todo_status(NEW.user_id, NEW.project_id,
+                                         CAST(CASE WHEN NEW.is_completed THEN 'done' ELSE 'todo' END AS status_category));
+        END IF;
+    ELSIF NEW.status_id IS DISTINCT FROM OLD.status_id THEN
+        NULL;
+    ELSIF NEW.is_completed IS DISTINCT FROM OLD.is_completed THEN
+        NEW.status_id := todo_status(NEW.user_id, NEW.project_id,
+                                     CAST(CASE WHEN NEW.is_completed THEN 'done' ELSE 'todo' END AS status_category));
+    ELSIF NEW.project_id IS DISTINCT FROM OLD.project_id THEN
+        SELECT * INTO current_status FROM statuses WHERE id = OLD.status_id;
+        IF current_status.project_id IS DISTINCT FROM workflow_project(NEW.user_id, NEW.project_id) THEN
+            NEW.status_id := COALESCE(todo_status(NEW.user_id, NEW.project_id, current_status.category),
+                                      todo_status(NEW.user_id, NEW.project_id, 'todo'));
+        END IF;
+    END IF;
+
+    NEW.is_completed := (SELECT category = 'done' FROM statuses WHERE id = NEW.status_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER todos_sync_status
+    BEFORE INSERT OR UPDATE
+    ON todos
+    FOR EACH ROW
+EXECUTE FUNCTION sync_todo_status();
+
+COMMIT;
@@ -0,0 +1,56 @@
+BEGIN;
+
+ALTER TABLE statuses
+    ADD COLUMN IF NOT EXISTS wip_limit INTEGER CONSTRAINT status_wip_limit CHECK (wip_limit > 0);
+
+-- check_wip_limit refuses to put one more live todo into a status that has
+-- reached its WIP limit, however it gets there: explicitly, by completing or
+-- reopening, by moving to another project, on insert or on restore from the
+-- trash. It runs after todos_sync_status, since triggers fire in name order,
+-- and so sees the final status. Setting todo.override_wip to 'on' for a
+-- transaction lets it exceed limits. Checks take the per user advisory lock
+-- of the 'status_wip' scope so that concurrent moves cannot both take the
+-- last free slot.
+CREATE OR REPLACE FUNCTION check_wip_limit() RETURNS TRIGGER AS
+$$
+DECLARE
+    target   statuses%ROWTYPE;
+    occupied INTEGER;
+BEGIN
+    IF NEW.archived_at IS NOT NULL
+        OR (TG_OP = 'UPDATE'
+            AND NEW.status_id IS NOT DISTINCT FROM OLD.status_id
+            AND OLD.archived_at IS NULL)
+        OR current_setting('todo.override_wip', true) = 'on' THEN
+        RETURN NEW;
+    END IF;
+
+    SELECT * INTO target FROM statuses WHERE id = NEW.status_id;
+    IF target.wip_limit IS NULL THEN
+        RETURN NEW;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(hashtext('status_wip'), hashtext(CAST(NEW.user_id AS TEXT)));
+
+    SELECT count(*)
+    INTO occupied
+    FROM todos
+    WHERE status_id = NEW.status_id
+      AND user_id = NEW.user_id
+      AND archived_at IS NULL
+      AND id <> NEW.id;
+
+    IF occupied >= target.wip_limit THEN
+        RAISE EXCEPTION 'status % has reached its WIP limit', target.name USING ERRCODE = 'TD001';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER todos_wip_limit
+    BEFORE INSERT OR UPDATE
+    ON todos
+    FOR EACH ROW
+EXECUTE FUNCTION check_wip_limit();
+
+COMMIT;
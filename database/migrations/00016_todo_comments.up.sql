@@ -0,0 +1,16 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS todo_comments
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    todo_id     UUID REFERENCES todos (id) NOT NULL,
+    user_id     UUID REFERENCES users (id) NOT NULL,
+    body        TEXT                       NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    edited_at   TIMESTAMP WITH TIME ZONE,
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS todo_comments_todo_id ON todo_comments (todo_id, created_at)
+    WHERE archived_at IS NULL;
+
+COMMIT;
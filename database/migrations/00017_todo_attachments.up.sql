@@ -0,0 +1,26 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS todo_attachments
+(
+    id           UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    todo_id      UUID REFERENCES todos (id) NOT NULL,
+    user_id      UUID REFERENCES users (id) NOT NULL,
+    file_name    TEXT                       NOT NULL,
+    content_type TEXT                       NOT NULL,
+    size         BIGINT                     NOT NULL,
+    storage_key  TEXT                       NOT NULL UNIQUE,
+    created_at   TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at  TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS todo_attachments_todo_id ON todo_attachments (todo_id, created_at)
+    WHERE archived_at IS NULL;
+
+-- blobs whose rows are gone wait here until the purger removed them from
+-- storage, which is not part of any transaction
+CREATE TABLE IF NOT EXISTS blob_deletions
+(
+    storage_key TEXT PRIMARY KEY,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+COMMIT;
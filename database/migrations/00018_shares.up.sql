@@ -0,0 +1,77 @@
+BEGIN;
+
+CREATE TYPE share_role AS ENUM ('viewer', 'editor');
+
+-- a share grants user_id access to a todo, including its subtasks, or to
+-- every todo of a project
+CREATE TABLE IF NOT EXISTS shares
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    owner_id    UUID REFERENCES users (id) NOT NULL,
+    user_id     UUID REFERENCES users (id) NOT NULL,
+    todo_id     UUID REFERENCES todos (id),
+    project_id  UUID REFERENCES projects (id),
+    role        share_role                 NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT share_target CHECK (num_nonnulls(todo_id, project_id) = 1),
+    CONSTRAINT share_self CHECK (owner_id <> user_id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS unique_todo_share ON shares (todo_id, user_id)
+    WHERE todo_id IS NOT NULL AND archived_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_project_share ON shares (project_id, user_id)
+    WHERE project_id IS NOT NULL AND archived_at IS NULL;
+CREATE INDEX IF NOT EXISTS shares_user_id ON shares (user_id) WHERE archived_at IS NULL;
+
+-- todo_role returns how viewer may access a todo: 'owner', 'editor',
+-- 'viewer' or NULL for no access. Shares of a parent todo or of the project
+-- apply as well, and the strongest one wins.
+CREATE OR REPLACE FUNCTION todo_role(todo UUID, viewer UUID) RETURNS TEXT AS
+$$
+WITH RECURSIVE chain AS (
+    SELECT id, parent_id, project_id, user_id
+    FROM todos
+    WHERE id = todo
+    UNION ALL
+    SELECT t.id, t.parent_id, t.project_id, t.user_id
+    FROM todos t
+             JOIN chain c ON t.id = c.parent_id
+)
+SELECT CASE
+           WHEN (SELECT user_id FROM todos WHERE id = todo) = viewer THEN 'owner'
+           ELSE (SELECT CAST(max(s.role) AS TEXT)
+                 FROM shares s
+                 WHERE s.user_id = viewer
+                   AND s.archived_at IS NULL
+                   AND (s.todo_id IN (SELECT id FROM chain)
+                     OR s.project_id IN (SELECT project_id FROM chain)))
+           END
+$$ LANGUAGE SQL STABLE;
+
+-- shared_todos lists every todo shared with viewer by someone else together
+-- with the strongest role viewer has on it
+CREATE OR REPLACE FUNCTION shared_todos(viewer UUID)
+    RETURNS TABLE
+            (
+                id   UUID,
+                role share_role
+            )
+AS
+$$
+WITH RECURSIVE shared AS (
+    SELECT t.id, s.role
+    FROM shares s
+             JOIN todos t ON t.id = s.todo_id OR t.project_id = s.project_id
+    WHERE s.user_id = viewer
+      AND s.archived_at IS NULL
+    UNION ALL
+    SELECT c.id, shared.role
+    FROM todos c
+             JOIN shared ON c.parent_id = shared.id
+)
+SELECT id, max(role)
+FROM shared
+GROUP BY id
+$$ LANGUAGE SQL STABLE;
+
+COMMIT;
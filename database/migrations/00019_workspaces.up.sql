@@ -0,0 +1,111 @@
+BEGIN;
+
+-- declared from the weakest to the strongest role so that max() picks the
+-- strongest one
+CREATE TYPE workspace_role AS ENUM ('guest', 'member', 'admin', 'owner');
+
+CREATE TABLE IF NOT EXISTS workspaces
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    name        TEXT                       NOT NULL,
+    created_by  UUID REFERENCES users (id) NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members
+(
+    id           UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    workspace_id UUID REFERENCES workspaces (id) NOT NULL,
+    user_id      UUID REFERENCES users (id)      NOT NULL,
+    role         workspace_role                  NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at  TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS unique_workspace_member ON workspace_members (workspace_id, user_id)
+    WHERE archived_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_workspace_owner ON workspace_members (workspace_id)
+    WHERE role = 'owner' AND archived_at IS NULL;
+CREATE INDEX IF NOT EXISTS workspace_members_user_id ON workspace_members (user_id) WHERE archived_at IS NULL;
+
+-- only a hash of the token is kept, the token itself is sent by email
+CREATE TABLE IF NOT EXISTS workspace_invitations
+(
+    id           UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    workspace_id UUID REFERENCES workspaces (id) NOT NULL,
+    email        TEXT                            NOT NULL,
+    role         workspace_role                  NOT NULL,
+    token_hash   TEXT                            NOT NULL UNIQUE,
+    invited_by   UUID REFERENCES users (id)      NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at   TIMESTAMP WITH TIME ZONE        NOT NULL,
+    accepted_at  TIMESTAMP WITH TIME ZONE,
+    archived_at  TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT invitation_role CHECK (role <> 'owner')
+);
+CREATE UNIQUE INDEX IF NOT EXISTS unique_invitation ON workspace_invitations (workspace_id, email)
+    WHERE accepted_at IS NULL AND archived_at IS NULL;
+
+-- projects without a workspace are personal. Todos belong to the workspace
+-- of their project.
+ALTER TABLE projects
+    ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces (id);
+CREATE INDEX IF NOT EXISTS projects_workspace_id ON projects (workspace_id) WHERE archived_at IS NULL;
+
+DROP INDEX IF EXISTS unique_project;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_project ON projects (user_id, name)
+    WHERE workspace_id IS NULL AND archived_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_workspace_project ON projects (workspace_id, name)
+    WHERE workspace_id IS NOT NULL AND archived_at IS NULL;
+
+-- workspace_role returns the role of viewer in a live workspace or NULL for
+-- non-members
+CREATE OR REPLACE FUNCTION workspace_role(workspace UUID, viewer UUID) RETURNS TEXT AS
+$$
+SELECT CAST(m.role AS TEXT)
+FROM workspace_members m
+         JOIN workspaces w ON w.id = m.workspace_id
+WHERE m.workspace_id = workspace
+  AND m.user_id = viewer
+  AND m.archived_at IS NULL
+  AND w.archived_at IS NULL
+$$ LANGUAGE SQL STABLE;
+
+-- member_share_role maps a workspace role to the access it gives to the
+-- todos of the workspace: guests may only read them
+CREATE OR REPLACE FUNCTION member_share_role(role TEXT) RETURNS share_role AS
+$$
+SELECT CAST(CASE WHEN role = 'guest' THEN 'viewer' ELSE 'editor' END AS share_role)
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- todo_role now also grants the members of a workspace access to the todos
+-- of its projects
+CREATE OR REPLACE FUNCTION todo_role(todo UUID, viewer UUID) RETURNS TEXT AS
+$$
+WITH RECURSIVE chain AS (
+    SELECT id, parent_id, project_id, user_id
+    FROM todos
+    WHERE id = todo
+    UNION ALL
+    SELECT t.id, t.parent_id, t.project_id, t.user_id
+    FROM todos t
+             JOIN chain c ON t.id = c.parent_id
+)
+SELECT CASE
+           WHEN (SELECT user_id FROM todos WHERE id = todo) = viewer THEN 'owner'
+           ELSE (SELECT CAST(max(role) AS TEXT)
+                 FROM (SELECT s.role
+                       FROM shares s
+                       WHERE s.user_id = viewer
+                         AND s.archived_at IS NULL
+                         AND (s.todo_id IN (SELECT id FROM chain)
+                           OR s.project_id IN (SELECT project_id FROM chain))
+                       UNION ALL
+                       SELECT member_share_role(workspace_role(p.workspace_id, viewer))
+                       FROM projects p
+                       WHERE p.id IN (SELECT project_id FROM chain)
+                         AND workspace_role(p.workspace_id, viewer) IS NOT NULL) roles)
+           END
+$$ LANGUAGE SQL STABLE;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS assignee_id UUID REFERENCES users (id);
+CREATE INDEX IF NOT EXISTS todos_assignee_id ON todos (assignee_id) WHERE archived_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- activity records who changed what. entity_id has no foreign key so that
+-- the history of a todo outlives the todo when it is purged.
+CREATE TABLE IF NOT EXISTS activity
+(
+    id         UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    actor_id   UUID REFERENCES users (id) NOT NULL,
+    entity     TEXT                       NOT NULL,
+    entity_id  UUID,
+    action     TEXT                       NOT NULL,
+    before     JSONB,
+    after      JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+);
+CREATE INDEX IF NOT EXISTS activity_actor ON activity (actor_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS activity_entity ON activity (entity, entity_id, created_at DESC, id DESC);
+
+COMMIT;
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"Todo/database/dbHelper"
+	"Todo/middlewares"
+	"Todo/models"
+	"Todo/utils"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	defaultActivityPageLimit = 50
+	maxActivityPageLimit     = 200
+)
+
+// GetActivity lists the activity log of the user, newest first, optionally
+// narrowed down with the entity and action query parameters.
+func GetActivity(w http.ResponseWriter, r *http.Request) {
+	filters, parseErr := parseActivityFilters(r.URL.Query())
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid query parameters")
+		return
+	}
+
+	respondActivity(w, r, filters)
+}
+
+// GetTodoHistory lists the activity log of a single todo. The history stays
+// readable once the todo is trashed or purged, as far as the user still has
+// access to the todo or made the changes themselves.
+func GetTodoHistory(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+
+	v := validator.New()
+	if err := v.Var(todoID, "uuid"); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "invalid todo id")
+		return
+	}
+
+	filters, parseErr := parseActivityFilters(r.URL.Query())
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid query parameters")
+		return
+	}
+	filters.TodoID = todoID
+	filters.Entity = string(models.ActivityEntityTodo)
+
+	respondActivity(w, r, filters)
+}
+
+func respondActivity(w http.ResponseWriter, r *http.Request, filters models.ActivityFilters) {
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	page, getErr := dbHelper.GetActivity(userID, filters)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get activity")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, page)
+}
+
+func parseActivityFilters(query url.Values) (models.ActivityFilters, error) {
+	filters := models.ActivityFilters{
+		Entity: query.Get("entity"),
+		Action: query.Get("action"),
+		Limit:  defaultActivityPageLimit,
+	}
+
+	switch models.ActivityEntity(filters.Entity) {
+	case "", models.ActivityEntityTodo, models.ActivityEntitySession, models.ActivityEntityUser,
+		models.ActivityEntityProject, models.ActivityEntityTag, models.ActivityEntityComment,
+		models.ActivityEntityAttachment, models.ActivityEntityShare, models.ActivityEntityWorkspace,
+		models.ActivityEntityMember, models.ActivityEntityInvitation:
+	default:
+		return filters, fmt.Errorf("unknown entity %q", filters.Entity)
+	}
+
+	switch models.ActivityAction(filters.Action) {
+	case "", models.ActivityCreate, models.ActivityUpdate, models.ActivityComplete, models.ActivityReopen,
+		models.ActivityDelete, models.ActivityRestore, models.ActivityPurge, models.ActivityLogin,
+		models.ActivityLogout, models.ActivityTag, models.ActivityUntag, models.ActivityBlock,
+		models.ActivityUnblock:
+	default:
+		return filters, fmt.Errorf("unknown action %q", filters.Action)
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxActivityPageLimit {
+			return filters, fmt.Errorf("limit must be between 1 and %d", maxActivityPageLimit)
+		}
+		filters.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		var cursor models.ActivityCursor
+		if err := utils.DecodeCursor(value, &cursor); err != nil {
+			return filters, fmt.Errorf("cursor: %w", err)
+		}
+		if err := validator.New().Var(cursor.ID, "uuid"); err != nil {
+			return filters, fmt.Errorf("cursor: %w", err)
+		}
+		filters.Cursor = &cursor
+	}
+	return filters, nil
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"Todo/database"
+	"Todo/database/dbHelper"
+	"Todo/middlewares"
+	"Todo/models"
+	"Todo/storage"
+	"Todo/utils"
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// multipartMemory is how much of an upload is buffered in memory before
+	// the rest is spooled to a temporary file.
+	multipartMemory = 1 << 20
+	// multipartOverhead leaves room for the boundaries and part headers on top
+	// of the file itself.
+	multipartOverhead = 64 << 10
+	maxFileNameLength = 255
+)
+
+// UploadAttachment stores the "file" part of a multipart form on a todo. The
+// content type is sniffed from the file rather than taken from the client.
+func UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+	limits := storage.Limits
+
+	r.Body = http.MaxBytesReader(w, r.Body, limits.MaxSize+multipartOverhead)
+	if parseErr := r.ParseMultipartForm(multipartMemory); parseErr != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(parseErr, &maxErr) {
+			utils.RespondError(w, http.StatusRequestEntityTooLarge, parseErr, "file is too large")
+			return
+		}
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse multipart form")
+		return
+	}
+	defer func() {
+		if err := r.MultipartForm.RemoveAll(); err != nil {
+			logrus.WithError(err).Error("failed to remove multipart temporary files")
+		}
+	}()
+
+	file, header, fileErr := r.FormFile("file")
+	if fileErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, fileErr, "file is required")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > limits.MaxSize {
+		utils.RespondError(w, http.StatusRequestEntityTooLarge, nil, "file is too large")
+		return
+	}
+
+	contentType, sniffErr := sniffContentType(file)
+	if sniffErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, sniffErr, "failed to read file")
+		return
+	}
+	if !limits.Allows(contentType) {
+		utils.RespondError(w, http.StatusUnsupportedMediaType, nil, "file type "+contentType+" is not allowed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	attachment := models.Attachment{
+		FileName:    attachmentFileName(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+	}
+	stored := false
+	crtErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		attachment, err = dbHelper.CreateAttachment(tx, todoID, userID, attachment)
+		if err != nil {
+			return err
+		}
+		err = dbHelper.RecordChange(tx, userID, models.ActivityEntityAttachment, attachment.ID, models.ActivityCreate,
+			nil, attachment)
+		if err != nil {
+			return err
+		}
+
+		if err = storage.Blobs.Put(r.Context(), attachment.StorageKey, file, attachment.Size, attachment.ContentType); err != nil {
+			return err
+		}
+		stored = true
+		return nil
+	})
+	if crtErr != nil {
+		// the blob is only orphaned if the commit itself failed
+		if stored {
+			if delErr := storage.Blobs.Delete(context.Background(), attachment.StorageKey); delErr != nil {
+				logrus.WithError(delErr).Errorf("failed to remove orphaned blob %s", attachment.StorageKey)
+			}
+		}
+		respondError(w, crtErr, "todo", "failed to upload attachment")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, attachment)
+}
+
+func GetAttachments(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	attachments, getErr := dbHelper.GetAttachments(todoID, userID)
+	if getErr != nil {
+		respondError(w, getErr, "todo", "failed to get attachments")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, attachments)
+}
+
+// DownloadAttachment streams the file of an attachment straight from storage.
+func DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+	attachmentID := chi.URLParam(r, "attachmentId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	attachment, getErr := dbHelper.GetAttachment(attachmentID, todoID, userID)
+	if getErr != nil {
+		respondAttachmentError(w, getErr, "failed to get attachment")
+		return
+	}
+
+	blob, blobErr := storage.Blobs.Get(r.Context(), attachment.StorageKey)
+	if blobErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, blobErr, "failed to read attachment")
+		return
+	}
+	defer blob.Close()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, blob); err != nil {
+		logrus.WithError(err).Errorf("failed to stream attachment %s", attachment.ID)
+	}
+}
+
+func DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+	attachmentID := chi.URLParam(r, "attachmentId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	delErr := database.Tx(func(tx *sqlx.Tx) error {
+		attachment, err := dbHelper.DeleteAttachment(tx, attachmentID, todoID, userID)
+		if err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityAttachment, attachmentID, models.ActivityDelete,
+			attachment, nil)
+	})
+	if delErr != nil {
+		respondAttachmentError(w, delErr, "failed to delete attachment")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"attachment deleted successfully"})
+}
+
+// sniffContentType detects the type of a file from its first bytes and
+// rewinds it for the upload.
+func sniffContentType(file multipart.File) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+func attachmentFileName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "attachment"
+	}
+	if len(name) > maxFileNameLength {
+		name = strings.ToValidUTF8(name[:maxFileNameLength], "")
+	}
+	return name
+}
+
+// respondAttachmentError answers errors of an attachment lookup, where
+// archived and unknown ids refer to the todo and ErrAttachmentNotFound to the
+// attachment.
+func respondAttachmentError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, dbHelper.ErrAttachmentNotFound) {
+		utils.RespondError(w, http.StatusNotFound, err, "attachment not found")
+		return
+	}
+	respondError(w, err, "todo", message)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"Todo/database/dbHelper"
+	"Todo/middlewares"
+	"Todo/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultBoardColumnLimit = 50
+	maxBoardColumnLimit     = 200
+)
+
+// GetBoard shows the todos of a workflow grouped by status. ?project= picks
+// the project and ?limit= caps the number of todos listed per column; the
+// column counts are not affected by it.
+func GetBoard(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	projectID := query.Get("project")
+
+	limit := defaultBoardColumnLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxBoardColumnLimit {
+			limitErr := fmt.Errorf("limit must be between 1 and %d", maxBoardColumnLimit)
+			utils.RespondError(w, http.StatusBadRequest, limitErr, "invalid query parameters")
+			return
+		}
+		limit = parsed
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	if err := checkProjectOwned(projectID, userID); err != nil {
+		respondError(w, err, "project", "failed to check project existence")
+		return
+	}
+
+	board, getErr := dbHelper.GetBoard(userID, projectID, limit)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get board")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, board)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"Todo/database"
+	"Todo/middlewares"
+	"Todo/models"
+	"Todo/utils"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// errBulkAborted stops an atomic bulk request at its first failing operation.
+var errBulkAborted = errors.New("bulk operation failed")
+
+// BulkTodos runs a list of todo operations in a single transaction. In atomic
+// mode, the default, the first failure rolls back everything and the request
+// fails with the status of that operation. In best effort mode every
+// operation runs in its own savepoint so that failures only undo themselves.
+// ?override_wip=true lifts WIP limits for all operations alike.
+func BulkTodos(w http.ResponseWriter, r *http.Request) {
+	var body models.BulkTodoRequest
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	overrideWIP, parseErr := parseOptionalBool(r.URL.Query().Get("override_wip"))
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid override_wip parameter")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+	workspaceID := selectedWorkspace(r)
+	atomic := body.Mode != models.BulkModeBestEffort
+
+	response := models.BulkTodoResponse{
+		Results: make([]models.BulkTodoResult, len(body.Operations)),
+	}
+	for i, operation := range body.Operations {
+		response.Results[i] = models.BulkTodoResult{Index: i, Op: operation.Op, Status: models.BulkResultSkipped}
+	}
+
+	failedStatus := 0
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		// set before the first savepoint so that no rollback undoes it
+		if err := overrideWIPLimits(tx, overrideWIP); err != nil {
+			return err
+		}
+
+		for i, operation := range body.Operations {
+			result := &response.Results[i]
+
+			var todoID string
+			opErr := database.Savepoint(tx, "bulk_operation", func() error {
+				var err error
+				todoID, err = runBulkOperation(tx, userID, workspaceID, operation)
+				return err
+			})
+			if opErr == nil {
+				result.Status = models.BulkResultOK
+				result.TodoID = todoID
+				continue
+			}
+
+			status, message := todoErrorStatus(opErr, "failed to "+string(operation.Op)+" todo")
+			if status == http.StatusInternalServerError {
+				logrus.Errorf("bulk operation %d (%s) failed: %+v", i, operation.Op, opErr)
+			}
+			result.Status = models.BulkResultFailed
+			result.StatusCode = status
+			result.Error = message
+			if failedStatus == 0 {
+				failedStatus = status
+			}
+			if atomic {
+				return errBulkAborted
+			}
+		}
+		return nil
+	})
+	if txErr != nil && !errors.Is(txErr, errBulkAborted) {
+		utils.RespondError(w, http.StatusInternalServerError, txErr, "failed to run bulk operations")
+		return
+	}
+
+	if txErr != nil {
+		for i := range response.Results {
+			if response.Results[i].Status == models.BulkResultOK {
+				response.Results[i].Status = models.BulkResultRolledBack
+			}
+		}
+		utils.RespondJSON(w, failedStatus, response)
+		return
+	}
+
+	response.Committed = true
+	utils.RespondJSON(w, http.StatusOK, response)
+}
+
+// runBulkOperation executes a single operation of a bulk request and returns
+// the id of the todo it affected.
+func runBulkOperation(tx *sqlx.Tx, userID, workspaceID string, operation models.BulkTodoOperation) (string, error) {
+	switch operation.Op {
+	case models.BulkOperationCreate:
+		body := *operation.Todo
+		body.UserID = userID
+		body.ParentID = ""
+		if err := prepareTodoRequest(&body); err != nil {
+			return "", err
+		}
+		if err := resolveTodoProject(&body, workspaceID); err != nil {
+			return "", err
+		}
+		return createTodo(tx, userID, body)
+	case models.BulkOperationUpdate:
+		body, err := prepareTodoPatch(bytes.NewReader(operation.Patch), operation.TodoID, userID)
+		if err != nil {
+			return "", err
+		}
+		_, err = updateTodo(tx, operation.TodoID, userID, body)
+		return operation.TodoID, err
+	case models.BulkOperationComplete:
+		return operation.TodoID, completeTodo(tx, operation.TodoID, userID, operation.Cascade, operation.Force)
+	case models.BulkOperationDelete:
+		return operation.TodoID, deleteTodo(tx, operation.TodoID, userID)
+	case models.BulkOperationMove:
+		if err := checkTodoMove(operation.TodoID, userID, operation.ProjectID); err != nil {
+			return "", err
+		}
+		body := models.UpdateTodoRequest{ProjectID: &operation.ProjectID}
+		_, err := updateTodo(tx, operation.TodoID, userID, body)
+		return operation.TodoID, err
+	default:
+		return "", fmt.Errorf("unsupported operation %q", operation.Op)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"Todo/database"
+	"Todo/database/dbHelper"
+	"Todo/middlewares"
+	"Todo/models"
+	"Todo/utils"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+	"strings"
+)
+
+func CreateComment(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+
+	body, parseErr := parseCommentRequest(r)
+	if parseErr != nil {
+		respondError(w, parseErr, "todo", "failed to create comment")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	var comment models.Comment
+	crtErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		if comment, err = dbHelper.CreateComment(tx, todoID, userID, body.Body); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityComment, comment.ID, models.ActivityCreate,
+			nil, comment)
+	})
+	if crtErr != nil {
+		respondError(w, crtErr, "todo", "failed to create comment")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, comment)
+}
+
+func GetComments(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	comments, getErr := dbHelper.GetComments(todoID, userID)
+	if getErr != nil {
+		respondError(w, getErr, "todo", "failed to get comments")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, comments)
+}
+
+func UpdateComment(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+	commentID := chi.URLParam(r, "commentId")
+
+	body, parseErr := parseCommentRequest(r)
+	if parseErr != nil {
+		respondError(w, parseErr, "comment", "failed to update comment")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	var comment models.Comment
+	updErr := database.Tx(func(tx *sqlx.Tx) error {
+		before, err := dbHelper.LockComment(tx, commentID, todoID, userID)
+		if err != nil {
+			return err
+		}
+		if comment, err = dbHelper.UpdateComment(tx, commentID, todoID, userID, body.Body); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityComment, commentID, models.ActivityUpdate,
+			before, comment)
+	})
+	if updErr != nil {
+		respondCommentError(w, updErr, "failed to update comment")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, comment)
+}
+
+func DeleteComment(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+	commentID := chi.URLParam(r, "commentId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	delErr := database.Tx(func(tx *sqlx.Tx) error {
+		comment, err := dbHelper.DeleteComment(tx, commentID, todoID, userID)
+		if err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityComment, commentID, models.ActivityDelete,
+			comment, nil)
+	})
+	if delErr != nil {
+		respondCommentError(w, delErr, "failed to delete comment")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"comment deleted successfully"})
+}
+
+// parseCommentRequest decodes a comment and rejects bodies that are blank
+// once trimmed.
+func parseCommentRequest(r *http.Request) (models.CommentRequest, error) {
+	var body models.CommentRequest
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		return body, badRequest(parseErr, "failed to parse request body")
+	}
+
+	body.Body = strings.TrimSpace(body.Body)
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		return body, badRequest(err, "input validation failed")
+	}
+	return body, nil
+}
+
+// respondCommentError answers errors of a comment lookup, where archived and
+// unknown ids refer to the todo and ErrCommentNotFound to the comment.
+func respondCommentError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, dbHelper.ErrCommentNotFound) {
+		utils.RespondError(w, http.StatusNotFound, err, "comment not found")
+		return
+	}
+	respondError(w, err, "todo", message)
+}
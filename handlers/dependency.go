@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"Todo/database"
+	"Todo/database/dbHelper"
+	"Todo/middlewares"
+	"Todo/models"
+	"Todo/utils"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+)
+
+func AddTodoBlockers(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+	var body models.TodoBlockersRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	crtErr := database.Tx(func(tx *sqlx.Tx) error {
+		added, err := dbHelper.AddTodoBlockers(tx, todoID, userID, body.BlockerIDs)
+		if err != nil || len(added) == 0 {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityTodo, todoID, models.ActivityBlock, nil, added)
+	})
+	if crtErr != nil {
+		switch {
+		case errors.Is(crtErr, dbHelper.ErrBlockerNotFound):
+			utils.RespondError(w, http.StatusBadRequest, crtErr, "blocker not found")
+		case errors.Is(crtErr, dbHelper.ErrDependencyCycle):
+			utils.RespondError(w, http.StatusConflict, crtErr, "dependency would create a cycle")
+		default:
+			respondError(w, crtErr, "todo", "failed to add blockers")
+		}
+		return
+	}
+
+	todo, getErr := dbHelper.GetTodo(todoID, userID)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get todo")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, todo)
+}
+
+func RemoveTodoBlocker(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+	blockerID := chi.URLParam(r, "blockerId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	delErr := database.Tx(func(tx *sqlx.Tx) error {
+		if err := dbHelper.RemoveTodoBlocker(tx, todoID, blockerID, userID); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityTodo, todoID, models.ActivityUnblock,
+			[]string{blockerID}, nil)
+	})
+	if delErr != nil {
+		respondError(w, delErr, "dependency", "failed to remove blocker")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"blocker removed successfully"})
+}
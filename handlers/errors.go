@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"Todo/database/dbHelper"
+	"Todo/utils"
+	"errors"
+	"net/http"
+)
+
+// requestError is returned by the helpers shared between handlers when the
+// request itself is invalid, so that it ends up as a 400 with its message.
+type requestError struct {
+	message string
+	err     error
+}
+
+func badRequest(err error, message string) error {
+	return &requestError{message: message, err: err}
+}
+
+func (e *requestError) Error() string {
+	if e.err == nil {
+		return e.message
+	}
+	return e.message + ": " + e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// errorStatus maps request errors and the sentinel errors shared by all
+// dbHelper lookups to their status codes and messages. Anything else is a 500
+// with message.
+func errorStatus(err error, entity, message string) (int, string) {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		return http.StatusBadRequest, reqErr.message
+	case errors.Is(err, dbHelper.ErrInvalidUUID):
+		return http.StatusBadRequest, "invalid id"
+	case errors.Is(err, dbHelper.ErrNotFound):
+		return http.StatusNotFound, entity + " not found"
+	case errors.Is(err, dbHelper.ErrAlreadyArchived):
+		return http.StatusConflict, entity + " is archived"
+	case errors.Is(err, dbHelper.ErrForbidden):
+		return http.StatusForbidden, "not allowed to change this " + entity
+	default:
+		return http.StatusInternalServerError, message
+	}
+}
+
+func respondError(w http.ResponseWriter, err error, entity, message string) {
+	status, messageToUser := errorStatus(err, entity, message)
+	utils.RespondError(w, status, err, messageToUser)
+}
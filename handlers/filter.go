@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"Todo/database/dbHelper"
+	"Todo/middlewares"
+	"Todo/models"
+	"Todo/utils"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+)
+
+func CreateSavedFilter(w http.ResponseWriter, r *http.Request) {
+	body, parseErr := parseSavedFilterRequest(r)
+	if parseErr != nil {
+		respondError(w, parseErr, "filter", "failed to create filter")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	filter, crtErr := dbHelper.CreateSavedFilter(userID, body)
+	if crtErr != nil {
+		if errors.Is(crtErr, dbHelper.ErrFilterAlreadyExists) {
+			utils.RespondError(w, http.StatusConflict, crtErr, "filter already exists")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, crtErr, "failed to create filter")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, filter)
+}
+
+func GetAllSavedFilters(w http.ResponseWriter, r *http.Request) {
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	filters, getErr := dbHelper.GetAllSavedFilters(userID)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get filters")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, filters)
+}
+
+func GetSavedFilter(w http.ResponseWriter, r *http.Request) {
+	filterID := chi.URLParam(r, "filterId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	filter, getErr := dbHelper.GetSavedFilter(filterID, userID)
+	if getErr != nil {
+		respondError(w, getErr, "filter", "failed to get filter")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, filter)
+}
+
+func UpdateSavedFilter(w http.ResponseWriter, r *http.Request) {
+	filterID := chi.URLParam(r, "filterId")
+
+	body, parseErr := parseSavedFilterRequest(r)
+	if parseErr != nil {
+		respondError(w, parseErr, "filter", "failed to update filter")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	filter, updErr := dbHelper.UpdateSavedFilter(filterID, userID, body)
+	if updErr != nil {
+		switch {
+		case errors.Is(updErr, dbHelper.ErrFilterAlreadyExists):
+			utils.RespondError(w, http.StatusConflict, updErr, "filter already exists")
+		default:
+			respondError(w, updErr, "filter", "failed to update filter")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, filter)
+}
+
+func DeleteSavedFilter(w http.ResponseWriter, r *http.Request) {
+	filterID := chi.URLParam(r, "filterId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	if delErr := dbHelper.DeleteSavedFilter(filterID, userID); delErr != nil {
+		respondError(w, delErr, "filter", "failed to delete filter")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"filter deleted successfully"})
+}
+
+// GetSavedFilterTodos lists the todos matching a saved filter, paginated by
+// the limit and cursor query parameters just like GET /v1/todo/.
+func GetSavedFilterTodos(w http.ResponseWriter, r *http.Request) {
+	filterID := chi.URLParam(r, "filterId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	filter, getErr := dbHelper.GetSavedFilter(filterID, userID)
+	if getErr != nil {
+		respondError(w, getErr, "filter", "failed to get filter")
+		return
+	}
+
+	filters, criteriaErr := criteriaFilters(filter.Criteria)
+	if criteriaErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, criteriaErr, "saved filter is no longer valid")
+		return
+	}
+	if pageErr := parseTodoPage(r.URL.Query(), &filters); pageErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, pageErr, "invalid query parameters")
+		return
+	}
+
+	page, todosErr := dbHelper.GetAllTodos(userID, selectedWorkspace(r), filters)
+	if todosErr != nil {
+		if errors.Is(todosErr, dbHelper.ErrCursorMismatch) {
+			utils.RespondError(w, http.StatusBadRequest, todosErr, "invalid cursor")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, todosErr, "failed to get todos")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, page)
+}
+
+// parseSavedFilterRequest decodes a saved filter and checks that its criteria
+// would be accepted by GET /v1/todo/.
+func parseSavedFilterRequest(r *http.Request) (models.SavedFilterRequest, error) {
+	var body models.SavedFilterRequest
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		return body, badRequest(parseErr, "failed to parse request body")
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		return body, badRequest(err, "input validation failed")
+	}
+
+	if _, err := criteriaFilters(body.Criteria); err != nil {
+		return body, badRequest(err, "invalid filter criteria")
+	}
+	return body, nil
+}
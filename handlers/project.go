@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"Todo/database"
+	"Todo/database/dbHelper"
+	"Todo/middlewares"
+	"Todo/models"
+	"Todo/utils"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+)
+
+func CreateProject(w http.ResponseWriter, r *http.Request) {
+	var body models.ProjectRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	workspace := middlewares.WorkspaceContext(r)
+	workspaceID := ""
+	if workspace != nil {
+		if !workspace.Role.CanEdit() {
+			respondError(w, dbHelper.ErrForbidden, "workspace", "failed to create project")
+			return
+		}
+		workspaceID = workspace.WorkspaceID
+	}
+
+	var project models.Project
+	crtErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		if project, err = dbHelper.CreateProject(tx, userID, workspaceID, body.Name); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityProject, project.ID, models.ActivityCreate,
+			nil, project)
+	})
+	if crtErr != nil {
+		if errors.Is(crtErr, dbHelper.ErrProjectAlreadyExists) {
+			utils.RespondError(w, http.StatusConflict, crtErr, "project already exists")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, crtErr, "failed to create project")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, project)
+}
+
+func GetAllProjects(w http.ResponseWriter, r *http.Request) {
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	projects, getErr := dbHelper.GetAllProjects(userID, selectedWorkspace(r))
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get projects")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, projects)
+}
+
+func RenameProject(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	var body models.ProjectRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	var project models.Project
+	updErr := database.Tx(func(tx *sqlx.Tx) error {
+		before, err := dbHelper.LockProject(tx, projectID, userID)
+		if err != nil {
+			return err
+		}
+		if project, err = dbHelper.RenameProject(tx, projectID, userID, body.Name); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityProject, projectID, models.ActivityUpdate,
+			before, project)
+	})
+	if updErr != nil {
+		switch {
+		case errors.Is(updErr, dbHelper.ErrProjectAlreadyExists):
+			utils.RespondError(w, http.StatusConflict, updErr, "project already exists")
+		default:
+			respondError(w, updErr, "project", "failed to rename project")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, project)
+}
+
+func DeleteProject(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	inboxID, inboxErr := dbHelper.GetInboxProjectID(userID)
+	if inboxErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, inboxErr, "failed to find inbox project")
+		return
+	}
+	if projectID == inboxID {
+		utils.RespondError(w, http.StatusBadRequest, nil, "inbox project cannot be deleted")
+		return
+	}
+
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		before, err := dbHelper.ProjectTodoSnapshots(tx, projectID)
+		if err != nil {
+			return err
+		}
+		project, err := dbHelper.DeleteProject(tx, projectID, userID)
+		if err != nil {
+			return err
+		}
+		err = dbHelper.RecordChange(tx, userID, models.ActivityEntityProject, projectID, models.ActivityDelete,
+			project, nil)
+		if err != nil {
+			return err
+		}
+		return dbHelper.RecordTodoChanges(tx, userID, before)
+	})
+	if txErr != nil {
+		respondError(w, txErr, "project", "failed to delete project")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"project deleted successfully"})
+}
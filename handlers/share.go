@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"Todo/database"
+	"Todo/database/dbHelper"
+	"Todo/middlewares"
+	"Todo/models"
+	"Todo/utils"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+)
+
+func ShareTodo(w http.ResponseWriter, r *http.Request) {
+	createShare(w, r, models.ShareTargetTodo, chi.URLParam(r, "todoId"))
+}
+
+func GetTodoShares(w http.ResponseWriter, r *http.Request) {
+	getShares(w, r, models.ShareTargetTodo, chi.URLParam(r, "todoId"))
+}
+
+func UpdateTodoShare(w http.ResponseWriter, r *http.Request) {
+	updateShare(w, r, models.ShareTargetTodo, chi.URLParam(r, "todoId"))
+}
+
+func DeleteTodoShare(w http.ResponseWriter, r *http.Request) {
+	deleteShare(w, r, models.ShareTargetTodo, chi.URLParam(r, "todoId"))
+}
+
+func ShareProject(w http.ResponseWriter, r *http.Request) {
+	createShare(w, r, models.ShareTargetProject, chi.URLParam(r, "projectId"))
+}
+
+func GetProjectShares(w http.ResponseWriter, r *http.Request) {
+	getShares(w, r, models.ShareTargetProject, chi.URLParam(r, "projectId"))
+}
+
+func UpdateProjectShare(w http.ResponseWriter, r *http.Request) {
+	updateShare(w, r, models.ShareTargetProject, chi.URLParam(r, "projectId"))
+}
+
+func DeleteProjectShare(w http.ResponseWriter, r *http.Request) {
+	deleteShare(w, r, models.ShareTargetProject, chi.URLParam(r, "projectId"))
+}
+
+func createShare(w http.ResponseWriter, r *http.Request, target models.ShareTarget, itemID string) {
+	var body models.ShareRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	var share models.Share
+	crtErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		if share, err = dbHelper.CreateShare(tx, target, itemID, userID, body); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityShare, share.ID, models.ActivityCreate, nil, share)
+	})
+	if crtErr != nil {
+		respondShareError(w, crtErr, target, "failed to share "+string(target))
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, share)
+}
+
+func getShares(w http.ResponseWriter, r *http.Request, target models.ShareTarget, itemID string) {
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	shares, getErr := dbHelper.GetShares(target, itemID, userID)
+	if getErr != nil {
+		respondShareError(w, getErr, target, "failed to get shares")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, shares)
+}
+
+func updateShare(w http.ResponseWriter, r *http.Request, target models.ShareTarget, itemID string) {
+	shareID := chi.URLParam(r, "shareId")
+	var body models.UpdateShareRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	var share models.Share
+	updErr := database.Tx(func(tx *sqlx.Tx) error {
+		before, err := dbHelper.LockShare(tx, shareID, target, itemID, userID)
+		if err != nil {
+			return err
+		}
+		if share, err = dbHelper.UpdateShare(tx, shareID, target, itemID, userID, body.Role); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityShare, shareID, models.ActivityUpdate,
+			before, share)
+	})
+	if updErr != nil {
+		respondShareError(w, updErr, target, "failed to update share")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, share)
+}
+
+func deleteShare(w http.ResponseWriter, r *http.Request, target models.ShareTarget, itemID string) {
+	shareID := chi.URLParam(r, "shareId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	delErr := database.Tx(func(tx *sqlx.Tx) error {
+		share, err := dbHelper.DeleteShare(tx, shareID, target, itemID, userID)
+		if err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityShare, shareID, models.ActivityDelete,
+			share, nil)
+	})
+	if delErr != nil {
+		respondShareError(w, delErr, target, "failed to delete share")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"share deleted successfully"})
+}
+
+// respondShareError extends respondError with the errors of managing shares.
+// Only owners manage shares, so editors get ErrForbidden from the lookup of
+// a shared todo.
+func respondShareError(w http.ResponseWriter, err error, target models.ShareTarget, message string) {
+	switch {
+	case errors.Is(err, dbHelper.ErrShareUserNotFound):
+		utils.RespondError(w, http.StatusBadRequest, err, "no registered user with this email")
+	case errors.Is(err, dbHelper.ErrShareWithSelf):
+		utils.RespondError(w, http.StatusBadRequest, err, "cannot share with yourself")
+	case errors.Is(err, dbHelper.ErrShareAlreadyExists):
+		utils.RespondError(w, http.StatusConflict, err, "already shared with this user")
+	case errors.Is(err, dbHelper.ErrShareNotFound):
+		utils.RespondError(w, http.StatusNotFound, err, "share not found")
+	default:
+		respondError(w, err, string(target), message)
+	}
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"Todo/database"
+	"Todo/database/dbHelper"
+	"Todo/middlewares"
+	"Todo/models"
+	"Todo/utils"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+)
+
+func CreateStatus(w http.ResponseWriter, r *http.Request) {
+	var body models.StatusRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	if err := checkProjectOwned(body.ProjectID, userID); err != nil {
+		respondError(w, err, "project", "failed to check project existence")
+		return
+	}
+
+	var status models.Status
+	crtErr := database.Tx(func(tx *sqlx.Tx) error {
+		// the first status of a project moves its todos off the default workflow
+		var before []models.Todo
+		var err error
+		if body.ProjectID != "" {
+			if before, err = dbHelper.ProjectTodoSnapshots(tx, body.ProjectID); err != nil {
+				return err
+			}
+		}
+
+		if status, err = dbHelper.CreateStatus(tx, userID, body); err != nil {
+			return err
+		}
+		return dbHelper.RecordTodoChanges(tx, userID, before)
+	})
+	if crtErr != nil {
+		if errors.Is(crtErr, dbHelper.ErrStatusAlreadyExists) {
+			utils.RespondError(w, http.StatusConflict, crtErr, "status already exists")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, crtErr, "failed to create status")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, status)
+}
+
+// GetStatuses lists the workflow of the project given by ?project=, or the
+// default workflow without it.
+func GetStatuses(w http.ResponseWriter, r *http.Request) {
+	projectID := r.URL.Query().Get("project")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	if err := checkProjectOwned(projectID, userID); err != nil {
+		respondError(w, err, "project", "failed to check project existence")
+		return
+	}
+
+	statuses, getErr := dbHelper.GetStatuses(userID, projectID)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get statuses")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, statuses)
+}
+
+func UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	statusID := chi.URLParam(r, "statusId")
+	var body models.UpdateStatusRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	var status models.Status
+	updErr := database.Tx(func(tx *sqlx.Tx) error {
+		before, err := dbHelper.StatusTodoSnapshots(tx, statusID)
+		if err != nil {
+			return err
+		}
+		if status, err = dbHelper.UpdateStatus(tx, statusID, userID, body); err != nil {
+			return err
+		}
+		return dbHelper.RecordTodoChanges(tx, userID, before)
+	})
+	if updErr != nil {
+		switch {
+		case errors.Is(updErr, dbHelper.ErrStatusAlreadyExists):
+			utils.RespondError(w, http.StatusConflict, updErr, "status already exists")
+		case errors.Is(updErr, dbHelper.ErrLastStatus):
+			utils.RespondError(w, http.StatusConflict, updErr, "a workflow needs at least one todo and one done status")
+		case errors.Is(updErr, dbHelper.ErrTodoAlreadyExists):
+			utils.RespondError(w, http.StatusConflict, updErr, "reopened todos clash with open todos of the same name")
+		default:
+			respondError(w, updErr, "status", "failed to update status")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, status)
+}
+
+func ReorderStatuses(w http.ResponseWriter, r *http.Request) {
+	var body models.StatusOrderRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	if err := checkProjectOwned(body.ProjectID, userID); err != nil {
+		respondError(w, err, "project", "failed to check project existence")
+		return
+	}
+
+	var statuses []models.Status
+	updErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		statuses, err = dbHelper.ReorderStatuses(tx, userID, body.ProjectID, body.StatusIDs)
+		return err
+	})
+	if updErr != nil {
+		switch {
+		case errors.Is(updErr, dbHelper.ErrStatusOrderMismatch):
+			utils.RespondError(w, http.StatusBadRequest, updErr, "statusIds must list every status of the workflow once")
+		default:
+			respondError(w, updErr, "status", "failed to reorder statuses")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, statuses)
+}
+
+func DeleteStatus(w http.ResponseWriter, r *http.Request) {
+	statusID := chi.URLParam(r, "statusId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	delErr := database.Tx(func(tx *sqlx.Tx) error {
+		before, err := dbHelper.StatusTodoSnapshots(tx, statusID)
+		if err != nil {
+			return err
+		}
+		if err = dbHelper.DeleteStatus(tx, statusID, userID); err != nil {
+			return err
+		}
+		return dbHelper.RecordTodoChanges(tx, userID, before)
+	})
+	if delErr != nil {
+		switch {
+		case errors.Is(delErr, dbHelper.ErrLastStatus):
+			utils.RespondError(w, http.StatusConflict, delErr, "a workflow needs at least one todo and one done status")
+		default:
+			respondError(w, delErr, "status", "failed to delete status")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"status deleted successfully"})
+}
+
+// TransitionTodo moves a todo to another status of its workflow. Entering a
+// done status completes the todo like MarkCompleted does, including the check
+// for open blockers unless ?force=true, and leaving one reopens it. A status
+// at its WIP limit takes no more todos unless ?override_wip=true.
+func TransitionTodo(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+	var body models.TodoStatusRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	force, parseErr := parseOptionalBool(r.URL.Query().Get("force"))
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid force parameter")
+		return
+	}
+
+	overrideWIP, parseErr := parseOptionalBool(r.URL.Query().Get("override_wip"))
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid override_wip parameter")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		if err := overrideWIPLimits(tx, overrideWIP); err != nil {
+			return err
+		}
+
+		// completing may schedule the next occurrence into the list
+		if err := dbHelper.LockTodoPositions(tx, todoID); err != nil {
+			return err
+		}
+
+		todo, err := dbHelper.LockTodo(tx, todoID, userID)
+		if err != nil {
+			return err
+		}
+
+		// shared todos follow the workflow of their owner
+		status, err := dbHelper.GetWorkflowStatus(tx, body.StatusID, todo.UserID, todo.ProjectID)
+		if err != nil {
+			return err
+		}
+
+		completes := status.Category == models.StatusCategoryDone && !todo.IsCompleted
+		if completes && !force {
+			if err = checkBlockers(tx, todoID, userID, false); err != nil {
+				return err
+			}
+		}
+
+		if err = dbHelper.SetTodoStatus(tx, todoID, userID, status); err != nil {
+			return err
+		}
+
+		action := models.ActivityUpdate
+		switch {
+		case completes:
+			action = models.ActivityComplete
+		case todo.IsCompleted && status.Category != models.StatusCategoryDone:
+			action = models.ActivityReopen
+		}
+		if err = dbHelper.RecordTodoActivity(tx, userID, todoID, action, &todo); err != nil {
+			return err
+		}
+
+		if !completes || todo.RecurrenceRule == nil {
+			return nil
+		}
+		return scheduleNextOccurrence(tx, todo, userID)
+	})
+	if txErr != nil {
+		switch {
+		case errors.Is(txErr, dbHelper.ErrStatusNotFound):
+			utils.RespondError(w, http.StatusBadRequest, txErr, "status is not part of the todo's workflow")
+		case errors.Is(txErr, dbHelper.ErrWIPLimitReached):
+			utils.RespondError(w, http.StatusConflict, txErr, "status has reached its WIP limit")
+		case errors.Is(txErr, dbHelper.ErrTodoAlreadyExists):
+			utils.RespondError(w, http.StatusConflict, txErr, "an open todo with the same name already exists")
+		default:
+			status, message := todoErrorStatus(txErr, "failed to change todo status")
+			utils.RespondError(w, status, txErr, message)
+		}
+		return
+	}
+
+	todo, getErr := dbHelper.GetTodo(todoID, userID)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get todo")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, todo)
+}
+
+// checkProjectOwned accepts an empty projectID, which stands for the default
+// workflow.
+func checkProjectOwned(projectID, userID string) error {
+	if projectID == "" {
+		return nil
+	}
+
+	owned, err := dbHelper.IsProjectOwned(projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return dbHelper.ErrNotFound
+	}
+	return nil
+}
+
+// overrideWIPLimits lifts the WIP limits for the rest of the transaction when
+// the request asked for it with ?override_wip=true. Every request that may
+// put a todo into a status, be it explicitly, by completing or reopening, by
+// moving it to another project, by creating one or by restoring one, takes the
+// flag, since the database refuses such changes into a full status otherwise.
+func overrideWIPLimits(tx *sqlx.Tx, override bool) error {
+	if !override {
+		return nil
+	}
+	return dbHelper.OverrideWIPLimits(tx)
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"Todo/database"
+	"Todo/database/dbHelper"
+	"Todo/middlewares"
+	"Todo/models"
+	"Todo/utils"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+)
+
+func CreateTag(w http.ResponseWriter, r *http.Request) {
+	var body models.TagRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	var tag models.Tag
+	crtErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		if tag, err = dbHelper.CreateTag(tx, body.Name, userID); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityTag, tag.ID, models.ActivityCreate, nil, tag)
+	})
+	if crtErr != nil {
+		if errors.Is(crtErr, dbHelper.ErrTagAlreadyExists) {
+			utils.RespondError(w, http.StatusConflict, crtErr, "tag already exists")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, crtErr, "failed to create tag")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, tag)
+}
+
+func GetAllTags(w http.ResponseWriter, r *http.Request) {
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	tags, getErr := dbHelper.GetAllTags(userID)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get tags")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, tags)
+}
+
+func RenameTag(w http.ResponseWriter, r *http.Request) {
+	tagID := chi.URLParam(r, "tagId")
+	var body models.TagRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	var tag models.Tag
+	updErr := database.Tx(func(tx *sqlx.Tx) error {
+		before, err := dbHelper.LockTag(tx, tagID, userID)
+		if err != nil {
+			return err
+		}
+		if tag, err = dbHelper.RenameTag(tx, tagID, userID, body.Name); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityTag, tagID, models.ActivityUpdate, before, tag)
+	})
+	if updErr != nil {
+		switch {
+		case errors.Is(updErr, dbHelper.ErrTagAlreadyExists):
+			utils.RespondError(w, http.StatusConflict, updErr, "tag already exists")
+		default:
+			respondError(w, updErr, "tag", "failed to rename tag")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, tag)
+}
+
+func DeleteTag(w http.ResponseWriter, r *http.Request) {
+	tagID := chi.URLParam(r, "tagId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	delErr := database.Tx(func(tx *sqlx.Tx) error {
+		tag, err := dbHelper.DeleteTag(tx, tagID, userID)
+		if err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityTag, tagID, models.ActivityDelete, tag, nil)
+	})
+	if delErr != nil {
+		respondError(w, delErr, "tag", "failed to delete tag")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"tag deleted successfully"})
+}
+
+func AttachTags(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+	var body models.TodoTagsRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	crtErr := database.Tx(func(tx *sqlx.Tx) error {
+		attached, err := dbHelper.AttachTags(tx, todoID, userID, body.TagIDs)
+		if err != nil || len(attached) == 0 {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityTodo, todoID, models.ActivityTag, nil, attached)
+	})
+	if crtErr != nil {
+		switch {
+		case errors.Is(crtErr, dbHelper.ErrTagNotFound):
+			utils.RespondError(w, http.StatusBadRequest, crtErr, "tag not found")
+		default:
+			respondError(w, crtErr, "todo", "failed to attach tags")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"tags attached successfully"})
+}
+
+func DetachTag(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+	tagID := chi.URLParam(r, "tagId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	delErr := database.Tx(func(tx *sqlx.Tx) error {
+		if err := dbHelper.DetachTag(tx, todoID, tagID, userID); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityTodo, todoID, models.ActivityUntag,
+			[]string{tagID}, nil)
+	})
+	if delErr != nil {
+		respondError(w, delErr, "tag", "failed to detach tag")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"tag detached successfully"})
+}
@@ -1,13 +1,24 @@
 package handlers
 
 import (
+	"Todo/database"
 	"Todo/database/dbHelper"
 	"Todo/middlewares"
 	"Todo/models"
+	"Todo/position"
+	"Todo/recurrence"
 	"Todo/utils"
+	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func CreateTodo(w http.ResponseWriter, r *http.Request) {
@@ -20,24 +31,42 @@ func CreateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	v := validator.New()
-	if err := v.Struct(body); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+	if err := prepareTodoRequest(&body); err != nil {
+		respondError(w, err, "todo", "failed to create todo")
 		return
 	}
 
-	exists, existsErr := dbHelper.IsTodoExists(body.Name, body.UserID)
+	if err := resolveTodoProject(&body, selectedWorkspace(r)); err != nil {
+		respondError(w, err, "project", "failed to check project existence")
+		return
+	}
+
+	exists, existsErr := dbHelper.IsTodoExists(database.Todo, body.Name, body.ProjectID)
 	if existsErr != nil {
 		utils.RespondError(w, http.StatusInternalServerError, existsErr, "failed to check todo existence")
 		return
 	}
 	if exists {
-		utils.RespondError(w, http.StatusBadRequest, nil, "todo already exists")
+		utils.RespondError(w, http.StatusConflict, nil, "todo already exists")
 		return
 	}
 
-	if saveErr := dbHelper.CreateTodo(body); saveErr != nil {
-		utils.RespondError(w, http.StatusInternalServerError, saveErr, "failed to create todo")
+	overrideWIP, parseErr := parseOptionalBool(r.URL.Query().Get("override_wip"))
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid override_wip parameter")
+		return
+	}
+
+	saveErr := database.Tx(func(tx *sqlx.Tx) error {
+		if err := overrideWIPLimits(tx, overrideWIP); err != nil {
+			return err
+		}
+		_, err := createTodo(tx, userCtx.UserID, body)
+		return err
+	})
+	if saveErr != nil {
+		status, message := todoErrorStatus(saveErr, "failed to create todo")
+		utils.RespondError(w, status, saveErr, message)
 		return
 	}
 
@@ -47,30 +76,498 @@ func CreateTodo(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetAllTodos(w http.ResponseWriter, r *http.Request) {
-	keyword := r.URL.Query().Get("keyword")
-	completed := r.URL.Query().Get("completed")
+	filters, parseErr := parseTodoFilters(r)
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid query parameters")
+		return
+	}
 
 	userCtx := middlewares.UserContext(r)
 	userID := userCtx.UserID
 
-	todos, getErr := dbHelper.GetAllTodos(userID, keyword, completed)
+	page, getErr := dbHelper.GetAllTodos(userID, selectedWorkspace(r), filters)
 	if getErr != nil {
+		if errors.Is(getErr, dbHelper.ErrCursorMismatch) {
+			utils.RespondError(w, http.StatusBadRequest, getErr, "invalid cursor")
+			return
+		}
 		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get todos")
 		return
 	}
 
-	utils.RespondJSON(w, http.StatusOK, todos)
+	utils.RespondJSON(w, http.StatusOK, page)
 }
 
-func MarkCompleted(w http.ResponseWriter, r *http.Request) {
+// GetAssignedTodos lists the todos assigned to the user across all projects,
+// no matter which workspace they belong to. It takes the same query
+// parameters as GetAllTodos.
+func GetAssignedTodos(w http.ResponseWriter, r *http.Request) {
+	filters, parseErr := parseTodoFilters(r)
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid query parameters")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	page, getErr := dbHelper.GetAssignedTodos(userID, filters)
+	if getErr != nil {
+		if errors.Is(getErr, dbHelper.ErrCursorMismatch) {
+			utils.RespondError(w, http.StatusBadRequest, getErr, "invalid cursor")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get todos")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, page)
+}
+
+// AssignTodo assigns a todo to a user who has access to it, or unassigns it
+// when assigneeId is null.
+func AssignTodo(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+	var body models.AssignTodoRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		previous, err := dbHelper.LockTodo(tx, todoID, userID)
+		if err != nil {
+			return err
+		}
+		if err = dbHelper.AssignTodo(tx, todoID, body.AssigneeID); err != nil {
+			return err
+		}
+		return dbHelper.RecordTodoActivity(tx, userID, todoID, models.ActivityUpdate, &previous)
+	})
+	if txErr != nil {
+		if errors.Is(txErr, dbHelper.ErrAssigneeNoAccess) {
+			utils.RespondError(w, http.StatusBadRequest, txErr, "assignee has no access to the todo")
+			return
+		}
+		respondError(w, txErr, "todo", "failed to assign todo")
+		return
+	}
+
+	todo, getErr := dbHelper.GetTodo(todoID, userID)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get todo")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, todo)
+}
+
+func UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	todoID := chi.URLParam(r, "todoId")
 
 	userCtx := middlewares.UserContext(r)
 	userID := userCtx.UserID
 
-	updErr := dbHelper.MarkCompleted(todoID, userID)
+	body, prepErr := prepareTodoPatch(r.Body, todoID, userID)
+	if prepErr != nil {
+		respondError(w, prepErr, "todo", "failed to update todo")
+		return
+	}
+
+	overrideWIP, parseErr := parseOptionalBool(r.URL.Query().Get("override_wip"))
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid override_wip parameter")
+		return
+	}
+
+	var todo models.Todo
+	updErr := database.Tx(func(tx *sqlx.Tx) error {
+		if err := overrideWIPLimits(tx, overrideWIP); err != nil {
+			return err
+		}
+		var err error
+		todo, err = updateTodo(tx, todoID, userID, body)
+		return err
+	})
 	if updErr != nil {
-		utils.RespondError(w, http.StatusInternalServerError, updErr, "failed to mark todo completed")
+		status, message := todoErrorStatus(updErr, "failed to update todo")
+		utils.RespondError(w, status, updErr, message)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, todo)
+}
+
+// prepareTodoPatch decodes and validates the merge patch of a todo.
+func prepareTodoPatch(r io.Reader, todoID, userID string) (models.UpdateTodoRequest, error) {
+	var body models.UpdateTodoRequest
+	nulls, parseErr := utils.ParseMergePatch(r, &body)
+	if parseErr != nil {
+		return body, badRequest(parseErr, "failed to parse request body")
+	}
+
+	if nulls["name"] || nulls["description"] || nulls["isCompleted"] || nulls["priority"] || nulls["projectId"] {
+		return body, badRequest(nil, "name, description, isCompleted, priority and projectId cannot be removed")
+	}
+	body.ClearDueAt = nulls["dueAt"]
+	body.ClearStartAt = nulls["startAt"]
+	body.ClearRecurrence = nulls["recurrenceRule"]
+	if nulls["recurrenceTimezone"] && !body.ClearRecurrence {
+		return body, badRequest(nil, "recurrenceTimezone can only be removed together with recurrenceRule")
+	}
+
+	if body.RecurrenceRule != nil {
+		timezone := ""
+		if body.RecurrenceTimezone != nil {
+			timezone = *body.RecurrenceTimezone
+		}
+		rule, _, recErr := normalizeRecurrence(*body.RecurrenceRule, timezone)
+		if recErr != nil {
+			return body, badRequest(recErr, "invalid recurrence")
+		}
+		body.RecurrenceRule = &rule
+	} else if body.RecurrenceTimezone != nil {
+		if _, tzErr := time.LoadLocation(*body.RecurrenceTimezone); tzErr != nil {
+			return body, badRequest(tzErr, "invalid recurrence")
+		}
+	}
+
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		body.Name = &name
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		return body, badRequest(err, "input validation failed")
+	}
+
+	if body.ProjectID != nil {
+		if err := checkTodoMove(todoID, userID, *body.ProjectID); err != nil {
+			return body, err
+		}
+	}
+	return body, nil
+}
+
+// checkTodoMove makes sure a todo may be moved into a project: only the owner
+// moves a todo, the project has to belong to them as well and subtasks only
+// ever move with their parent.
+func checkTodoMove(todoID, userID, projectID string) error {
+	current, getErr := dbHelper.GetTodo(todoID, userID)
+	if getErr != nil {
+		return getErr
+	}
+	if current.UserID != userID {
+		return dbHelper.ErrForbidden
+	}
+	if current.ParentID != nil {
+		return badRequest(nil, "subtasks always belong to the project of their parent")
+	}
+
+	owned, ownedErr := dbHelper.IsProjectOwned(projectID, userID)
+	if ownedErr != nil {
+		return ownedErr
+	}
+	if !owned {
+		return badRequest(nil, "project not found")
+	}
+	return nil
+}
+
+// updateTodo applies a validated patch to a locked todo, moves its subtasks
+// along with it, logs the change and schedules the next occurrence when a
+// recurring todo gets completed by the patch.
+func updateTodo(tx *sqlx.Tx, todoID, userID string, body models.UpdateTodoRequest) (models.Todo, error) {
+	if err := dbHelper.LockTodoPositions(tx, todoID); err != nil {
+		return models.Todo{}, err
+	}
+
+	previous, err := dbHelper.LockTodo(tx, todoID, userID)
+	if err != nil {
+		return previous, err
+	}
+
+	before, err := dbHelper.TodoTreeSnapshots(tx, todoID)
+	if err != nil {
+		return previous, err
+	}
+
+	if !previous.IsCompleted && body.IsCompleted != nil && *body.IsCompleted {
+		if err = checkBlockers(tx, todoID, userID, false); err != nil {
+			return previous, err
+		}
+	}
+
+	todo, err := dbHelper.UpdateTodo(tx, todoID, userID, body)
+	if err != nil {
+		return todo, err
+	}
+
+	if body.ProjectID != nil {
+		if err = dbHelper.MoveSubtasks(tx, todoID, *body.ProjectID); err != nil {
+			return todo, err
+		}
+	}
+
+	if err = dbHelper.RecordTodoChanges(tx, userID, before); err != nil {
+		return todo, err
+	}
+
+	if previous.IsCompleted || !todo.IsCompleted || todo.RecurrenceRule == nil {
+		return todo, nil
+	}
+	return todo, scheduleNextOccurrence(tx, todo, userID)
+}
+
+// checkBlockers fails with dbHelper.ErrTodoBlocked while a todo, or with
+// cascade one of its subtasks, still has open blockers.
+func checkBlockers(tx *sqlx.Tx, todoID, userID string, cascade bool) error {
+	open, err := dbHelper.CountOpenBlockers(tx, todoID, userID, cascade)
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return dbHelper.ErrTodoBlocked
+	}
+	return nil
+}
+
+// todoErrorStatus extends errorStatus with the errors of writing a todo.
+func todoErrorStatus(err error, message string) (int, string) {
+	switch {
+	case errors.Is(err, dbHelper.ErrTodoAlreadyExists):
+		return http.StatusConflict, "todo already exists"
+	case errors.Is(err, dbHelper.ErrInvalidSchedule):
+		return http.StatusBadRequest, "start date must not be after due date"
+	case errors.Is(err, dbHelper.ErrRecurrenceDueDate):
+		return http.StatusBadRequest, "recurring todos need a due date"
+	case errors.Is(err, dbHelper.ErrTodoBlocked):
+		return http.StatusConflict, "todo is blocked by open todos"
+	case errors.Is(err, dbHelper.ErrWIPLimitReached):
+		return http.StatusConflict, "status has reached its WIP limit"
+	default:
+		return errorStatus(err, "todo", message)
+	}
+}
+
+func CreateSubtask(w http.ResponseWriter, r *http.Request) {
+	parentID := chi.URLParam(r, "todoId")
+
+	var body models.TodoRequest
+	userCtx := middlewares.UserContext(r)
+	body.UserID = userCtx.UserID
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	if err := prepareTodoRequest(&body); err != nil {
+		respondError(w, err, "todo", "failed to create subtask")
+		return
+	}
+
+	role, roleErr := dbHelper.GetTodoRole(parentID, body.UserID)
+	if roleErr == nil && !role.CanEdit() {
+		roleErr = dbHelper.ErrForbidden
+	}
+	if roleErr != nil {
+		respondError(w, roleErr, "todo", "failed to get todo")
+		return
+	}
+
+	parent, getErr := dbHelper.GetTodo(parentID, body.UserID)
+	if getErr != nil {
+		respondError(w, getErr, "todo", "failed to get todo")
+		return
+	}
+	// subtasks of a shared todo belong to its owner like the todo itself
+	body.UserID = parent.UserID
+	body.ParentID = parent.ID
+	body.ProjectID = parent.ProjectID
+
+	exists, existsErr := dbHelper.IsSubtaskExists(database.Todo, body.Name, body.ParentID)
+	if existsErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, existsErr, "failed to check subtask existence")
+		return
+	}
+	if exists {
+		utils.RespondError(w, http.StatusConflict, nil, "subtask already exists")
+		return
+	}
+
+	overrideWIP, parseErr := parseOptionalBool(r.URL.Query().Get("override_wip"))
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid override_wip parameter")
+		return
+	}
+
+	saveErr := database.Tx(func(tx *sqlx.Tx) error {
+		if err := overrideWIPLimits(tx, overrideWIP); err != nil {
+			return err
+		}
+		_, err := createTodo(tx, userCtx.UserID, body)
+		return err
+	})
+	if saveErr != nil {
+		status, message := todoErrorStatus(saveErr, "failed to create subtask")
+		utils.RespondError(w, status, saveErr, message)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"subtask created successfully"})
+}
+
+// createTodo saves a validated todo or subtask and logs its creation by
+// actorID, who is not the owner in body when creating in a shared project or
+// under a shared parent.
+func createTodo(tx *sqlx.Tx, actorID string, body models.TodoRequest) (string, error) {
+	todoID, err := dbHelper.CreateTodo(tx, body)
+	if err != nil {
+		return todoID, err
+	}
+	return todoID, dbHelper.RecordTodoActivity(tx, actorID, todoID, models.ActivityCreate, nil)
+}
+
+// prepareTodoRequest validates a new todo or subtask and fills in the
+// defaults of its optional fields.
+func prepareTodoRequest(body *models.TodoRequest) error {
+	// names are stored trimmed, so a blank name would end up empty
+	body.Name = strings.TrimSpace(body.Name)
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		return badRequest(err, "input validation failed")
+	}
+
+	if body.StartAt != nil && body.DueAt != nil && body.StartAt.After(*body.DueAt) {
+		return badRequest(nil, "start date must not be after due date")
+	}
+
+	if body.Priority == "" {
+		body.Priority = models.PriorityNone
+	}
+
+	if body.RecurrenceRule != "" {
+		rule, timezone, recErr := normalizeRecurrence(body.RecurrenceRule, body.RecurrenceTimezone)
+		if recErr != nil {
+			return badRequest(recErr, "invalid recurrence")
+		}
+		if body.DueAt == nil {
+			return badRequest(nil, "recurring todos need a due date")
+		}
+		body.RecurrenceRule, body.RecurrenceTimezone = rule, timezone
+	}
+	return nil
+}
+
+// resolveTodoProject puts a new todo into the inbox unless it names a project
+// of its owner or a project shared with them as editor. Todos created in a
+// shared project belong to the owner of the project. Within a workspace the
+// project has to be one of the workspace, since the inbox is personal.
+func resolveTodoProject(body *models.TodoRequest, workspaceID string) error {
+	if body.ProjectID == "" {
+		if workspaceID != "" {
+			return badRequest(nil, "projectId is required in a workspace")
+		}
+		inboxID, inboxErr := dbHelper.GetInboxProjectID(body.UserID)
+		if inboxErr != nil {
+			return inboxErr
+		}
+		body.ProjectID = inboxID
+		return nil
+	}
+
+	access, accessErr := dbHelper.GetProjectAccess(body.ProjectID, body.UserID)
+	if errors.Is(accessErr, dbHelper.ErrNotFound) {
+		return badRequest(nil, "project not found")
+	}
+	if accessErr != nil {
+		return accessErr
+	}
+	if workspaceID != "" && (access.WorkspaceID == nil || *access.WorkspaceID != workspaceID) {
+		return badRequest(nil, "project is not part of the workspace")
+	}
+	if !access.Role.CanEdit() {
+		return dbHelper.ErrForbidden
+	}
+	body.UserID = access.OwnerID
+	return nil
+}
+
+func GetSubtasks(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	todo, getErr := dbHelper.GetTodo(todoID, userID)
+	if getErr != nil {
+		respondError(w, getErr, "todo", "failed to get todo")
+		return
+	}
+
+	subtasks, treeErr := dbHelper.GetSubtaskTree(todoID, userID)
+	if treeErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, treeErr, "failed to get subtasks")
+		return
+	}
+	todo.Subtasks = subtasks
+
+	utils.RespondJSON(w, http.StatusOK, todo)
+}
+
+func MarkCompleted(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	cascade, parseErr := parseOptionalBool(r.URL.Query().Get("cascade"))
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid cascade parameter")
+		return
+	}
+
+	force, parseErr := parseOptionalBool(r.URL.Query().Get("force"))
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid force parameter")
+		return
+	}
+
+	overrideWIP, parseErr := parseOptionalBool(r.URL.Query().Get("override_wip"))
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid override_wip parameter")
+		return
+	}
+
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		if err := overrideWIPLimits(tx, overrideWIP); err != nil {
+			return err
+		}
+		return completeTodo(tx, todoID, userID, cascade, force)
+	})
+	if txErr != nil {
+		switch {
+		case errors.Is(txErr, dbHelper.ErrTodoAlreadyExists):
+			utils.RespondError(w, http.StatusConflict, txErr, "next occurrence clashes with an existing todo")
+		default:
+			status, message := todoErrorStatus(txErr, "failed to mark todo completed")
+			utils.RespondError(w, status, txErr, message)
+		}
 		return
 	}
 
@@ -79,15 +576,200 @@ func MarkCompleted(w http.ResponseWriter, r *http.Request) {
 	}{"todo marked completed successfully"})
 }
 
+// completeTodo marks a locked todo, and with cascade its open subtasks,
+// completed, logs it and schedules the next occurrence of a recurring todo. Unless
+// forced, todos with open blockers cannot be completed.
+func completeTodo(tx *sqlx.Tx, todoID, userID string, cascade, force bool) error {
+	if err := dbHelper.LockTodoPositions(tx, todoID); err != nil {
+		return err
+	}
+
+	todo, err := dbHelper.LockTodo(tx, todoID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !force {
+		if err = checkBlockers(tx, todoID, userID, cascade); err != nil {
+			return err
+		}
+	}
+
+	before, err := dbHelper.TodoTreeSnapshots(tx, todoID)
+	if err != nil {
+		return err
+	}
+
+	if err = dbHelper.MarkCompleted(tx, todoID, userID, cascade); err != nil {
+		return err
+	}
+
+	if err = dbHelper.RecordTodoChanges(tx, userID, before); err != nil {
+		return err
+	}
+
+	if todo.IsCompleted || todo.RecurrenceRule == nil {
+		return nil
+	}
+	return scheduleNextOccurrence(tx, todo, userID)
+}
+
+func MarkIncomplete(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	overrideWIP, parseErr := parseOptionalBool(r.URL.Query().Get("override_wip"))
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid override_wip parameter")
+		return
+	}
+
+	updErr := database.Tx(func(tx *sqlx.Tx) error {
+		if err := overrideWIPLimits(tx, overrideWIP); err != nil {
+			return err
+		}
+		todo, err := dbHelper.LockTodo(tx, todoID, userID)
+		if err != nil {
+			return err
+		}
+		if err = dbHelper.MarkIncomplete(tx, todoID, userID); err != nil {
+			return err
+		}
+		return dbHelper.RecordTodoActivity(tx, userID, todoID, models.ActivityReopen, &todo)
+	})
+	if updErr != nil {
+		switch {
+		case errors.Is(updErr, dbHelper.ErrTodoAlreadyExists):
+			utils.RespondError(w, http.StatusConflict, updErr, "an open todo with the same name already exists")
+		case errors.Is(updErr, dbHelper.ErrWIPLimitReached):
+			utils.RespondError(w, http.StatusConflict, updErr, "status has reached its WIP limit")
+		default:
+			respondError(w, updErr, "todo", "failed to mark todo incomplete")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"todo marked incomplete successfully"})
+}
+
+// MoveTodo places a todo right before or right after a neighbour in the
+// manual order, or between two neighbours when both are given. Only the moved
+// todo is written unless the neighbours' keys leave no room between them, in
+// which case the user's list is rebalanced first. Shared todos move within
+// the list of their owner.
+func MoveTodo(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+	var body models.MoveTodoRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+	if body.Before == todoID || body.After == todoID {
+		utils.RespondError(w, http.StatusBadRequest, nil, "a todo cannot be moved next to itself")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		if err := dbHelper.LockTodoPositions(tx, todoID); err != nil {
+			return err
+		}
+
+		todo, err := dbHelper.LockTodo(tx, todoID, userID)
+		if err != nil {
+			return err
+		}
+		ownerID := todo.UserID
+
+		key, err := movePosition(tx, todoID, ownerID, body)
+		if errors.Is(err, position.ErrInvalidRange) || errors.Is(err, position.ErrInvalidKey) {
+			if err = dbHelper.RebalanceTodoPositions(tx, ownerID); err != nil {
+				return err
+			}
+			key, err = movePosition(tx, todoID, ownerID, body)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = dbHelper.SetTodoPosition(tx, todoID, ownerID, key); err != nil {
+			return err
+		}
+		return dbHelper.RecordTodoActivity(tx, userID, todoID, models.ActivityUpdate, &todo)
+	})
+	if txErr != nil {
+		switch {
+		case errors.Is(txErr, position.ErrInvalidRange):
+			utils.RespondError(w, http.StatusBadRequest, txErr, "after must come before before in the list")
+		default:
+			respondError(w, txErr, "todo", "failed to move todo")
+		}
+		return
+	}
+
+	todo, getErr := dbHelper.GetTodo(todoID, userID)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get todo")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, todo)
+}
+
+// movePosition finds the key between the requested neighbours. A missing
+// neighbour is taken from the list itself, skipping the todo being moved.
+func movePosition(tx *sqlx.Tx, todoID, userID string, body models.MoveTodoRequest) (string, error) {
+	var lower, upper string
+	var err error
+
+	if body.After != "" {
+		if lower, err = dbHelper.GetTodoPosition(tx, body.After, userID); err != nil {
+			return "", err
+		}
+	}
+	if body.Before != "" {
+		if upper, err = dbHelper.GetTodoPosition(tx, body.Before, userID); err != nil {
+			return "", err
+		}
+	}
+
+	switch {
+	case body.Before == "":
+		upper, err = dbHelper.NextTodoPosition(tx, userID, lower, todoID)
+	case body.After == "":
+		lower, err = dbHelper.PreviousTodoPosition(tx, userID, upper, todoID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return position.Between(lower, upper)
+}
+
 func DeleteTodo(w http.ResponseWriter, r *http.Request) {
 	todoID := chi.URLParam(r, "todoId")
 
 	userCtx := middlewares.UserContext(r)
 	userID := userCtx.UserID
 
-	delErr := dbHelper.DeleteTodo(todoID, userID)
+	delErr := database.Tx(func(tx *sqlx.Tx) error {
+		return deleteTodo(tx, todoID, userID)
+	})
 	if delErr != nil {
-		utils.RespondError(w, http.StatusInternalServerError, delErr, "failed to delete todo")
+		respondError(w, delErr, "todo", "failed to delete todo")
 		return
 	}
 
@@ -96,11 +778,196 @@ func DeleteTodo(w http.ResponseWriter, r *http.Request) {
 	}{"todo deleted successfully"})
 }
 
+// deleteTodo moves a todo and its subtasks to the trash and logs it.
+func deleteTodo(tx *sqlx.Tx, todoID, userID string) error {
+	before, err := dbHelper.TodoTreeSnapshots(tx, todoID)
+	if err != nil {
+		return err
+	}
+	if err = dbHelper.DeleteTodo(tx, todoID, userID); err != nil {
+		return err
+	}
+	return dbHelper.RecordTodoChanges(tx, userID, before)
+}
+
+func GetTrashedTodos(w http.ResponseWriter, r *http.Request) {
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	todos, getErr := dbHelper.GetTrashedTodos(userID)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get trashed todos")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, todos)
+}
+
+// maxRestoreSuffix bounds the search for a free name when a restored todo
+// clashes with a live one.
+const maxRestoreSuffix = 100
+
+func RestoreTodo(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	onConflict := r.URL.Query().Get("on_conflict")
+	if onConflict != "" && onConflict != "fail" && onConflict != "rename" {
+		utils.RespondError(w, http.StatusBadRequest, nil, "on_conflict must be fail or rename")
+		return
+	}
+
+	overrideWIP, parseErr := parseOptionalBool(r.URL.Query().Get("override_wip"))
+	if parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "invalid override_wip parameter")
+		return
+	}
+
+	var restored models.Todo
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		if err := overrideWIPLimits(tx, overrideWIP); err != nil {
+			return err
+		}
+
+		todo, err := dbHelper.LockTrashedTodo(tx, todoID, userID)
+		if err != nil {
+			return err
+		}
+		before, err := dbHelper.TodoTreeSnapshots(tx, todoID)
+		if err != nil {
+			return err
+		}
+
+		if todo.ParentID != nil {
+			if _, err = dbHelper.GetTodo(*todo.ParentID, userID); err != nil {
+				if errors.Is(err, dbHelper.ErrNotFound) || errors.Is(err, dbHelper.ErrAlreadyArchived) {
+					return errParentTrashed
+				}
+				return err
+			}
+		} else {
+			owned, err := dbHelper.IsProjectOwned(todo.ProjectID, userID)
+			if err != nil {
+				return err
+			}
+			if !owned {
+				// the project was deleted in the meantime
+				if todo.ProjectID, err = dbHelper.GetInboxProjectID(userID); err != nil {
+					return err
+				}
+			}
+		}
+
+		name, err := freeTodoName(tx, todo, onConflict == "rename")
+		if err != nil {
+			return err
+		}
+
+		if err = dbHelper.RestoreTodo(tx, todoID, userID, name, todo.ProjectID); err != nil {
+			return err
+		}
+		if err = dbHelper.RecordTodoChanges(tx, userID, before); err != nil {
+			return err
+		}
+		restored = todo
+		return nil
+	})
+	if txErr != nil {
+		switch {
+		case errors.Is(txErr, errParentTrashed):
+			utils.RespondError(w, http.StatusConflict, txErr, "restore the parent todo first")
+		case errors.Is(txErr, dbHelper.ErrTodoAlreadyExists):
+			utils.RespondError(w, http.StatusConflict, txErr, "a todo with the same name already exists")
+		case errors.Is(txErr, dbHelper.ErrWIPLimitReached):
+			utils.RespondError(w, http.StatusConflict, txErr, "status has reached its WIP limit")
+		default:
+			respondError(w, txErr, "trashed todo", "failed to restore todo")
+		}
+		return
+	}
+
+	todo, getErr := dbHelper.GetTodo(restored.ID, userID)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get todo")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, todo)
+}
+
+var errParentTrashed = errors.New("parent todo is in the trash")
+
+// freeTodoName returns the name a trashed todo can be restored under. A clash
+// with a live todo is an error unless rename is set, in which case the first
+// free "name (n)" is used instead. The names are checked in the transaction
+// of the restore, which has the todo locked.
+func freeTodoName(tx *sqlx.Tx, todo models.Todo, rename bool) (string, error) {
+	exists := func(name string) (bool, error) {
+		if todo.ParentID != nil {
+			return dbHelper.IsSubtaskExists(tx, name, *todo.ParentID)
+		}
+		return dbHelper.IsTodoExists(tx, name, todo.ProjectID)
+	}
+
+	taken, err := exists(todo.Name)
+	if err != nil || !taken {
+		return todo.Name, err
+	}
+	if !rename {
+		return "", dbHelper.ErrTodoAlreadyExists
+	}
+
+	for i := 1; i <= maxRestoreSuffix; i++ {
+		name := fmt.Sprintf("%s (%d)", todo.Name, i)
+		if taken, err = exists(name); err != nil || !taken {
+			return name, err
+		}
+	}
+	return "", dbHelper.ErrTodoAlreadyExists
+}
+
+func PurgeTodo(w http.ResponseWriter, r *http.Request) {
+	todoID := chi.URLParam(r, "todoId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	delErr := database.Tx(func(tx *sqlx.Tx) error {
+		before, err := dbHelper.TodoTreeSnapshots(tx, todoID)
+		if err != nil {
+			return err
+		}
+		if err = dbHelper.PurgeTodo(tx, todoID, userID); err != nil {
+			return err
+		}
+		return dbHelper.RecordTodoChanges(tx, userID, before)
+	})
+	if delErr != nil {
+		respondError(w, delErr, "trashed todo", "failed to permanently delete todo")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"todo permanently deleted successfully"})
+}
+
 func DeleteAllTodos(w http.ResponseWriter, r *http.Request) {
 	userCtx := middlewares.UserContext(r)
 	userID := userCtx.UserID
 
-	delErr := dbHelper.DeleteAllTodos(userID)
+	delErr := database.Tx(func(tx *sqlx.Tx) error {
+		todos, err := dbHelper.OwnedTodoSnapshots(tx, userID)
+		if err != nil {
+			return err
+		}
+		if err = dbHelper.DeleteAllTodos(tx, userID); err != nil {
+			return err
+		}
+		return dbHelper.RecordTodoChanges(tx, userID, todos)
+	})
 	if delErr != nil {
 		utils.RespondError(w, http.StatusInternalServerError, delErr, "failed to delete todos")
 		return
@@ -110,3 +977,289 @@ func DeleteAllTodos(w http.ResponseWriter, r *http.Request) {
 		Message string `json:"message"`
 	}{"all todos deleted successfully"})
 }
+
+const (
+	defaultTodoPageLimit = 50
+	maxTodoPageLimit     = 200
+)
+
+func parseTodoFilters(r *http.Request) (models.TodoFilters, error) {
+	query := r.URL.Query()
+
+	criteria, parseErr := parseFilterCriteria(query)
+	if parseErr != nil {
+		return models.TodoFilters{}, parseErr
+	}
+
+	filters, criteriaErr := criteriaFilters(criteria)
+	if criteriaErr != nil {
+		return filters, criteriaErr
+	}
+	return filters, parseTodoPage(query, &filters)
+}
+
+// parseFilterCriteria reads the filtering and sorting parameters of a todo
+// listing, leaving their validation to criteriaFilters.
+func parseFilterCriteria(query url.Values) (models.FilterCriteria, error) {
+	criteria := models.FilterCriteria{
+		Keyword:   query.Get("keyword"),
+		Query:     query.Get("q"),
+		ProjectID: query.Get("project"),
+		TagMode:   query.Get("tag_mode"),
+		Sort:      query.Get("sort"),
+		Assignee:  query.Get("assignee"),
+	}
+
+	if value := query.Get("completed"); value != "" {
+		completed, err := strconv.ParseBool(value)
+		if err != nil {
+			return criteria, fmt.Errorf("completed: %w", err)
+		}
+		criteria.Completed = &completed
+	}
+
+	for param, target := range map[string]**time.Time{
+		"due_before":       &criteria.DueBefore,
+		"due_after":        &criteria.DueAfter,
+		"completed_before": &criteria.CompletedBefore,
+		"completed_after":  &criteria.CompletedAfter,
+	} {
+		if value := query.Get(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return criteria, fmt.Errorf("%s: %w", param, err)
+			}
+			*target = &t
+		}
+	}
+
+	for param, target := range map[string]*bool{
+		"overdue":           &criteria.Overdue,
+		"include_unstarted": &criteria.IncludeUnstarted,
+		"include_subtasks":  &criteria.IncludeSubtasks,
+	} {
+		b, err := parseOptionalBool(query.Get(param))
+		if err != nil {
+			return criteria, fmt.Errorf("%s: %w", param, err)
+		}
+		*target = b
+	}
+
+	for _, value := range query["tag"] {
+		criteria.Tags = append(criteria.Tags, strings.Split(value, ",")...)
+	}
+
+	return criteria, nil
+}
+
+// criteriaFilters validates filter criteria, whether they come from the query
+// string or from a saved filter, and turns them into todo filters.
+func criteriaFilters(criteria models.FilterCriteria) (models.TodoFilters, error) {
+	filters := models.TodoFilters{
+		Keyword:          criteria.Keyword,
+		Query:            strings.TrimSpace(criteria.Query),
+		ProjectID:        criteria.ProjectID,
+		DueBefore:        criteria.DueBefore,
+		DueAfter:         criteria.DueAfter,
+		CompletedBefore:  criteria.CompletedBefore,
+		CompletedAfter:   criteria.CompletedAfter,
+		Overdue:          criteria.Overdue,
+		IncludeUnstarted: criteria.IncludeUnstarted,
+		IncludeSubtasks:  criteria.IncludeSubtasks,
+		Assignee:         criteria.Assignee,
+	}
+
+	if filters.ProjectID != "" {
+		if err := validator.New().Var(filters.ProjectID, "uuid"); err != nil {
+			return filters, fmt.Errorf("project: %w", err)
+		}
+	}
+
+	switch filters.Assignee {
+	case "", models.AssigneeMe, models.AssigneeNone:
+	default:
+		if err := validator.New().Var(filters.Assignee, "uuid"); err != nil {
+			return filters, errors.New("assignee must be me, none or a user id")
+		}
+	}
+
+	if criteria.Completed != nil {
+		filters.Completed = strconv.FormatBool(*criteria.Completed)
+	}
+
+	seenTags := make(map[string]bool)
+	for _, tag := range criteria.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" && !seenTags[tag] {
+			seenTags[tag] = true
+			filters.Tags = append(filters.Tags, tag)
+		}
+	}
+
+	switch criteria.TagMode {
+	case "", "any":
+	case "all":
+		filters.MatchAllTags = true
+	default:
+		return filters, errors.New("tag_mode must be any or all")
+	}
+
+	if criteria.Sort != "" {
+		sorts, err := parseTodoSort(criteria.Sort)
+		if err != nil {
+			return filters, fmt.Errorf("sort: %w", err)
+		}
+		for _, sort := range sorts {
+			if sort.Key == models.TodoSortRelevance && filters.Query == "" {
+				return filters, errors.New("sort: relevance needs a search query")
+			}
+		}
+		filters.Sort = sorts
+	}
+
+	return filters, nil
+}
+
+// parseTodoPage reads the page size and cursor of a todo listing.
+func parseTodoPage(query url.Values, filters *models.TodoFilters) error {
+	filters.Limit = defaultTodoPageLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxTodoPageLimit {
+			return fmt.Errorf("limit must be between 1 and %d", maxTodoPageLimit)
+		}
+		filters.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		var cursor models.TodoCursor
+		if err := utils.DecodeCursor(value, &cursor); err != nil {
+			return fmt.Errorf("cursor: %w", err)
+		}
+		filters.Cursor = &cursor
+	}
+	return nil
+}
+
+var todoSortKeys = map[string]models.TodoSortKey{
+	string(models.TodoSortPriority):  models.TodoSortPriority,
+	string(models.TodoSortDueAt):     models.TodoSortDueAt,
+	string(models.TodoSortCreatedAt): models.TodoSortCreatedAt,
+	string(models.TodoSortName):      models.TodoSortName,
+	string(models.TodoSortPosition):  models.TodoSortPosition,
+	string(models.TodoSortRelevance): models.TodoSortRelevance,
+}
+
+// parseTodoSort parses a comma separated list of sort keys such as
+// "priority:desc,due_at" where the direction defaults to ascending.
+func parseTodoSort(value string) ([]models.TodoSort, error) {
+	fields := strings.Split(value, ",")
+	sorts := make([]models.TodoSort, 0, len(fields))
+	seen := make(map[models.TodoSortKey]bool, len(fields))
+
+	for _, field := range fields {
+		name, direction, _ := strings.Cut(strings.TrimSpace(field), ":")
+		key, ok := todoSortKeys[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported sort key %q", name)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate sort key %q", name)
+		}
+		seen[key] = true
+
+		sort := models.TodoSort{Key: key}
+		switch strings.ToLower(direction) {
+		case "", "asc":
+		case "desc":
+			sort.Desc = true
+		default:
+			return nil, fmt.Errorf("unsupported sort direction %q", direction)
+		}
+		sorts = append(sorts, sort)
+	}
+	return sorts, nil
+}
+
+// normalizeRecurrence validates a recurrence rule and its timezone and returns
+// them in the form they are stored in. The timezone defaults to UTC.
+func normalizeRecurrence(rule, timezone string) (string, string, error) {
+	if _, err := recurrence.Parse(rule); err != nil {
+		return "", "", err
+	}
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return "", "", fmt.Errorf("timezone: %w", err)
+	}
+	return recurrence.Normalize(rule), timezone, nil
+}
+
+// scheduleNextOccurrence creates the follow-up of a recurring todo that has
+// just been completed, unless its series has ended. The start date keeps its
+// distance to the due date and the follow-up is placed right after the todo,
+// rebalancing the list first when there is no room left. The follow-up is
+// logged as created by actorID, who completed the todo.
+func scheduleNextOccurrence(tx *sqlx.Tx, todo models.Todo, actorID string) error {
+	rule, parseErr := recurrence.Parse(*todo.RecurrenceRule)
+	if parseErr != nil {
+		return parseErr
+	}
+	if rule.Exhausted(todo.RecurrenceIndex) {
+		return nil
+	}
+
+	loc, locErr := time.LoadLocation(*todo.RecurrenceTimezone)
+	if locErr != nil {
+		return locErr
+	}
+
+	dueAt, ok := rule.Next(*todo.RecurrenceStart, *todo.DueAt, loc)
+	if !ok {
+		return nil
+	}
+
+	var startAt *time.Time
+	if todo.StartAt != nil {
+		shifted := todo.StartAt.Add(dueAt.Sub(*todo.DueAt))
+		startAt = &shifted
+	}
+
+	key, keyErr := occurrencePosition(tx, todo.ID, todo.UserID)
+	if errors.Is(keyErr, position.ErrInvalidRange) || errors.Is(keyErr, position.ErrInvalidKey) {
+		if keyErr = dbHelper.RebalanceTodoPositions(tx, todo.UserID); keyErr != nil {
+			return keyErr
+		}
+		key, keyErr = occurrencePosition(tx, todo.ID, todo.UserID)
+	}
+	if keyErr != nil {
+		return keyErr
+	}
+
+	nextID, crtErr := dbHelper.CreateNextOccurrence(tx, todo.ID, key, dueAt, startAt)
+	if crtErr != nil {
+		return crtErr
+	}
+	return dbHelper.RecordTodoActivity(tx, actorID, nextID, models.ActivityCreate, nil)
+}
+
+// occurrencePosition returns the position right after a recurring todo, where
+// its next occurrence goes.
+func occurrencePosition(tx *sqlx.Tx, todoID, ownerID string) (string, error) {
+	current, err := dbHelper.GetTodoPosition(tx, todoID, ownerID)
+	if err != nil {
+		return "", err
+	}
+	next, err := dbHelper.NextTodoPosition(tx, ownerID, current, todoID)
+	if err != nil {
+		return "", err
+	}
+	return position.Between(current, next)
+}
+
+func parseOptionalBool(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"Todo/models"
+	"strings"
+	"testing"
+)
+
+func TestPrepareTodoRequestName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		invalid bool
+	}{
+		{name: "groceries", want: "groceries"},
+		{name: "  groceries\t", want: "groceries"},
+		{name: "", invalid: true},
+		{name: "   ", invalid: true},
+		{name: "\t\n", invalid: true},
+	}
+
+	for _, tt := range tests {
+		body := models.TodoRequest{Name: tt.name, Description: "milk and eggs"}
+		err := prepareTodoRequest(&body)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("name %q: expected a validation error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("name %q: unexpected error %v", tt.name, err)
+			continue
+		}
+		if body.Name != tt.want {
+			t.Errorf("name %q: got %q, want %q", tt.name, body.Name, tt.want)
+		}
+	}
+}
+
+func TestPrepareTodoPatchName(t *testing.T) {
+	tests := []struct {
+		patch   string
+		want    string
+		invalid bool
+	}{
+		{patch: `{"name": "groceries"}`, want: "groceries"},
+		{patch: `{"name": " groceries "}`, want: "groceries"},
+		{patch: `{"name": ""}`, invalid: true},
+		{patch: `{"name": "   "}`, invalid: true},
+		{patch: `{"name": null}`, invalid: true},
+	}
+
+	for _, tt := range tests {
+		body, err := prepareTodoPatch(strings.NewReader(tt.patch), "", "")
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("patch %s: expected a validation error", tt.patch)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("patch %s: unexpected error %v", tt.patch, err)
+			continue
+		}
+		if body.Name == nil || *body.Name != tt.want {
+			t.Errorf("patch %s: got %v, want %q", tt.patch, body.Name, tt.want)
+		}
+	}
+}
@@ -41,7 +41,19 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if saveErr := dbHelper.CreateUser(body.Name, body.Email, hashedPassword); saveErr != nil {
+	saveErr := database.Tx(func(tx *sqlx.Tx) error {
+		userID, crtErr := dbHelper.CreateUser(tx, body.Name, body.Email, hashedPassword)
+		if crtErr != nil {
+			return crtErr
+		}
+
+		if err := dbHelper.CreateInboxProject(tx, userID); err != nil {
+			return err
+		}
+
+		return dbHelper.CreateDefaultStatuses(tx, userID)
+	})
+	if saveErr != nil {
 		utils.RespondError(w, http.StatusInternalServerError, saveErr, "failed to save user")
 		return
 	}
@@ -76,9 +88,23 @@ func LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionID, crtErr := dbHelper.CreateUserSession(userID)
-	if crtErr != nil {
-		utils.RespondError(w, http.StatusInternalServerError, crtErr, "failed to create user session")
+	var sessionID string
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		var crtErr error
+		sessionID, crtErr = dbHelper.CreateUserSession(tx, userID)
+		if crtErr != nil {
+			return crtErr
+		}
+
+		return dbHelper.RecordActivity(tx, models.Activity{
+			ActorID:  userID,
+			Entity:   models.ActivityEntitySession,
+			EntityID: &sessionID,
+			Action:   models.ActivityLogin,
+		})
+	})
+	if txErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, txErr, "failed to create user session")
 		return
 	}
 
@@ -111,8 +137,20 @@ func LogoutUser(w http.ResponseWriter, r *http.Request) {
 	userCtx := middlewares.UserContext(r)
 	sessionID := userCtx.SessionID
 
-	if delErr := dbHelper.DeleteUserSession(sessionID); delErr != nil {
-		utils.RespondError(w, http.StatusInternalServerError, delErr, "failed to delete user session")
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		if delErr := dbHelper.DeleteUserSession(tx, sessionID); delErr != nil {
+			return delErr
+		}
+
+		return dbHelper.RecordActivity(tx, models.Activity{
+			ActorID:  userCtx.UserID,
+			Entity:   models.ActivityEntitySession,
+			EntityID: &sessionID,
+			Action:   models.ActivityLogout,
+		})
+	})
+	if txErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, txErr, "failed to delete user session")
 		return
 	}
 
@@ -127,12 +165,21 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 	sessionID := userCtx.SessionID
 
 	txErr := database.Tx(func(tx *sqlx.Tx) error {
-		delErr := dbHelper.DeleteUser(userID)
+		delErr := dbHelper.DeleteUser(tx, userID)
 		if delErr != nil {
 			return delErr
 		}
 
-		return dbHelper.DeleteUserSession(sessionID)
+		if delErr = dbHelper.DeleteUserSession(tx, sessionID); delErr != nil {
+			return delErr
+		}
+
+		return dbHelper.RecordActivity(tx, models.Activity{
+			ActorID:  userID,
+			Entity:   models.ActivityEntityUser,
+			EntityID: &userID,
+			Action:   models.ActivityDelete,
+		})
 	})
 	if txErr != nil {
 		utils.RespondError(w, http.StatusInternalServerError, txErr, "failed to delete user account")
@@ -0,0 +1,503 @@
+package handlers
+
+import (
+	"Todo/database"
+	"Todo/database/dbHelper"
+	"Todo/mailer"
+	"Todo/middlewares"
+	"Todo/models"
+	"Todo/utils"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// invitationTTL is how long an invitation can be accepted.
+const invitationTTL = 7 * 24 * time.Hour
+
+var errInvitationNotSent = errors.New("invitation email not sent")
+
+func CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	var body models.WorkspaceRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	var workspace models.Workspace
+	crtErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		if workspace, err = dbHelper.CreateWorkspace(tx, userID, body.Name); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityWorkspace, workspace.ID, models.ActivityCreate,
+			nil, workspace)
+	})
+	if crtErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, crtErr, "failed to create workspace")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, workspace)
+}
+
+func GetWorkspaces(w http.ResponseWriter, r *http.Request) {
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	workspaces, getErr := dbHelper.GetWorkspaces(userID)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get workspaces")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, workspaces)
+}
+
+func GetWorkspace(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "workspaceId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	workspace, getErr := dbHelper.GetWorkspace(workspaceID, userID)
+	if getErr != nil {
+		respondError(w, getErr, "workspace", "failed to get workspace")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, workspace)
+}
+
+func RenameWorkspace(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "workspaceId")
+	var body models.WorkspaceRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	var workspace models.Workspace
+	updErr := database.Tx(func(tx *sqlx.Tx) error {
+		before, err := dbHelper.LockWorkspace(tx, workspaceID, userID)
+		if err != nil {
+			return err
+		}
+		if workspace, err = dbHelper.RenameWorkspace(tx, workspaceID, userID, body.Name); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityWorkspace, workspaceID, models.ActivityUpdate,
+			before, workspace)
+	})
+	if updErr != nil {
+		respondError(w, updErr, "workspace", "failed to rename workspace")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, workspace)
+}
+
+func DeleteWorkspace(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "workspaceId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		before, err := dbHelper.WorkspaceTodoSnapshots(tx, workspaceID)
+		if err != nil {
+			return err
+		}
+		workspace, err := dbHelper.DeleteWorkspace(tx, workspaceID, userID)
+		if err != nil {
+			return err
+		}
+		err = dbHelper.RecordChange(tx, userID, models.ActivityEntityWorkspace, workspaceID, models.ActivityDelete,
+			workspace, nil)
+		if err != nil {
+			return err
+		}
+		return dbHelper.RecordTodoChanges(tx, userID, before)
+	})
+	if txErr != nil {
+		respondError(w, txErr, "workspace", "failed to delete workspace")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"workspace deleted successfully"})
+}
+
+func GetWorkspaceMembers(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "workspaceId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	if _, roleErr := dbHelper.GetWorkspaceRole(workspaceID, userID); roleErr != nil {
+		respondError(w, roleErr, "workspace", "failed to check workspace membership")
+		return
+	}
+
+	members, getErr := dbHelper.GetWorkspaceMembers(workspaceID)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get members")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, members)
+}
+
+func UpdateWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "workspaceId")
+	memberID := chi.URLParam(r, "userId")
+	var body models.WorkspaceMemberRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	role, roleErr := dbHelper.GetWorkspaceRole(workspaceID, userID)
+	if roleErr != nil {
+		respondError(w, roleErr, "workspace", "failed to check workspace membership")
+		return
+	}
+
+	var member models.WorkspaceMember
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		current, err := dbHelper.LockWorkspaceMember(tx, workspaceID, memberID)
+		if err != nil {
+			return err
+		}
+		if err = checkRoleChange(role, current.Role, body.Role); err != nil {
+			return err
+		}
+
+		if member, err = dbHelper.UpdateWorkspaceMember(tx, workspaceID, memberID, body.Role); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityMember, memberID, models.ActivityUpdate,
+			current, member)
+	})
+	if txErr != nil {
+		respondWorkspaceError(w, txErr, "failed to update member")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, member)
+}
+
+// RemoveWorkspaceMember removes a member from a workspace. Members other than
+// the owner may also remove themselves to leave the workspace.
+func RemoveWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "workspaceId")
+	memberID := chi.URLParam(r, "userId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	role, roleErr := dbHelper.GetWorkspaceRole(workspaceID, userID)
+	if roleErr != nil {
+		respondError(w, roleErr, "workspace", "failed to check workspace membership")
+		return
+	}
+
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		current, err := dbHelper.LockWorkspaceMember(tx, workspaceID, memberID)
+		if err != nil {
+			return err
+		}
+		leaving := memberID == userID && current.Role != models.WorkspaceRoleOwner
+		if !leaving {
+			if err = checkRoleChange(role, current.Role, ""); err != nil {
+				return err
+			}
+		}
+
+		if err = dbHelper.RemoveWorkspaceMember(tx, workspaceID, memberID); err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityMember, memberID, models.ActivityDelete,
+			current, nil)
+	})
+	if txErr != nil {
+		respondWorkspaceError(w, txErr, "failed to remove member")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"member removed successfully"})
+}
+
+// InviteToWorkspace emails an invitation token to an address. The token is
+// only ever part of the email, the response holds the invitation without it.
+func InviteToWorkspace(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "workspaceId")
+	var body models.InvitationRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	workspace, getErr := dbHelper.GetWorkspace(workspaceID, userID)
+	if getErr != nil {
+		respondError(w, getErr, "workspace", "failed to get workspace")
+		return
+	}
+	if err := checkRoleChange(workspace.Role, "", body.Role); err != nil {
+		respondError(w, err, "workspace", "failed to invite member")
+		return
+	}
+
+	inviter, userErr := dbHelper.GetUser(userID)
+	if userErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, userErr, "failed to get user")
+		return
+	}
+
+	token, tokenErr := utils.GenerateToken()
+	if tokenErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, tokenErr, "failed to generate invitation token")
+		return
+	}
+
+	var invitation models.Invitation
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		invitation, err = dbHelper.CreateInvitation(tx, workspaceID, userID, utils.HashToken(token),
+			time.Now().Add(invitationTTL), body)
+		if err != nil {
+			return err
+		}
+		err = dbHelper.RecordChange(tx, userID, models.ActivityEntityInvitation, invitation.ID, models.ActivityCreate,
+			nil, invitation)
+		if err != nil {
+			return err
+		}
+
+		// sent before the commit so that a failed email leaves no invitation
+		// behind that nobody could accept
+		if err = mailer.Mail.Send(r.Context(), invitationMessage(workspace, inviter, invitation, token)); err != nil {
+			return fmt.Errorf("%w: %v", errInvitationNotSent, err)
+		}
+		return nil
+	})
+	if txErr != nil {
+		if errors.Is(txErr, errInvitationNotSent) {
+			utils.RespondError(w, http.StatusBadGateway, txErr, "failed to send invitation email")
+			return
+		}
+		respondWorkspaceError(w, txErr, "failed to invite member")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, invitation)
+}
+
+func GetInvitations(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "workspaceId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	role, roleErr := dbHelper.GetWorkspaceRole(workspaceID, userID)
+	if roleErr != nil {
+		respondError(w, roleErr, "workspace", "failed to check workspace membership")
+		return
+	}
+	if !role.CanManage() {
+		respondError(w, dbHelper.ErrForbidden, "workspace", "failed to get invitations")
+		return
+	}
+
+	invitations, getErr := dbHelper.GetInvitations(workspaceID)
+	if getErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, getErr, "failed to get invitations")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, invitations)
+}
+
+func RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "workspaceId")
+	invitationID := chi.URLParam(r, "invitationId")
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	role, roleErr := dbHelper.GetWorkspaceRole(workspaceID, userID)
+	if roleErr != nil {
+		respondError(w, roleErr, "workspace", "failed to check workspace membership")
+		return
+	}
+	if !role.CanManage() {
+		respondError(w, dbHelper.ErrForbidden, "workspace", "failed to revoke invitation")
+		return
+	}
+
+	delErr := database.Tx(func(tx *sqlx.Tx) error {
+		invitation, err := dbHelper.RevokeInvitation(tx, workspaceID, invitationID)
+		if err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityInvitation, invitationID, models.ActivityDelete,
+			invitation, nil)
+	})
+	if delErr != nil {
+		respondWorkspaceError(w, delErr, "failed to revoke invitation")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string `json:"message"`
+	}{"invitation revoked successfully"})
+}
+
+// AcceptInvitation joins the workspace of an invitation token. The invitation
+// has to be addressed to the email of the logged-in user.
+func AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var body models.AcceptInvitationRequest
+
+	if parseErr := utils.ParseBody(r.Body, &body); parseErr != nil {
+		utils.RespondError(w, http.StatusBadRequest, parseErr, "failed to parse request body")
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input validation failed")
+		return
+	}
+
+	userCtx := middlewares.UserContext(r)
+	userID := userCtx.UserID
+
+	var workspace models.Workspace
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		if workspace, err = dbHelper.AcceptInvitation(tx, utils.HashToken(body.Token), userID); err != nil {
+			return err
+		}
+
+		member, err := dbHelper.LockWorkspaceMember(tx, workspace.ID, userID)
+		if err != nil {
+			return err
+		}
+		return dbHelper.RecordChange(tx, userID, models.ActivityEntityMember, userID, models.ActivityCreate,
+			nil, member)
+	})
+	if txErr != nil {
+		respondWorkspaceError(w, txErr, "failed to accept invitation")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, workspace)
+}
+
+// checkRoleChange enforces who may give or take which role: owners and admins
+// manage members, the owner keeps their role and only the owner grants,
+// changes or removes admins. An empty current role stands for a new member
+// and an empty next role for a removed one.
+func checkRoleChange(actor, current, next models.WorkspaceRole) error {
+	switch {
+	case !actor.CanManage(), current == models.WorkspaceRoleOwner:
+		return dbHelper.ErrForbidden
+	case actor != models.WorkspaceRoleOwner &&
+		(current == models.WorkspaceRoleAdmin || next == models.WorkspaceRoleAdmin):
+		return dbHelper.ErrForbidden
+	}
+	return nil
+}
+
+func invitationMessage(workspace models.Workspace, inviter models.User, invitation models.Invitation, token string) mailer.Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s invited you to join the workspace %q as %s.\n\n", inviter.Name, workspace.Name, invitation.Role)
+	if mailer.AppURL != "" {
+		fmt.Fprintf(&body, "Accept the invitation at %s/invitations/accept?token=%s\n", mailer.AppURL, url.QueryEscape(token))
+		fmt.Fprintf(&body, "or with the token %s\n\n", token)
+	} else {
+		fmt.Fprintf(&body, "Accept the invitation with the token %s\n\n", token)
+	}
+	fmt.Fprintf(&body, "The invitation expires on %s.\n", invitation.ExpiresAt.UTC().Format(time.RFC1123))
+
+	return mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("%s invited you to %s", inviter.Name, workspace.Name),
+		Body:    body.String(),
+	}
+}
+
+// respondWorkspaceError extends respondError with the errors of managing the
+// members of a workspace.
+func respondWorkspaceError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, dbHelper.ErrMemberNotFound):
+		utils.RespondError(w, http.StatusNotFound, err, "member not found")
+	case errors.Is(err, dbHelper.ErrAlreadyMember):
+		utils.RespondError(w, http.StatusConflict, err, "already a member of the workspace")
+	case errors.Is(err, dbHelper.ErrAlreadyInvited):
+		utils.RespondError(w, http.StatusConflict, err, "an invitation for this email is already pending")
+	case errors.Is(err, dbHelper.ErrInvitationNotFound):
+		utils.RespondError(w, http.StatusNotFound, err, "invitation not found or expired")
+	default:
+		respondError(w, err, "workspace", message)
+	}
+}
+
+// selectedWorkspace returns the workspace a request works in, or an empty
+// string for personal projects.
+func selectedWorkspace(r *http.Request) string {
+	if workspace := middlewares.WorkspaceContext(r); workspace != nil {
+		return workspace.WorkspaceID
+	}
+	return ""
+}
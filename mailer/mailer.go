@@ -0,0 +1,101 @@
+// Package mailer sends the emails of the application, such as workspace
+// invitations. The backend is picked through configuration so that
+// development setups can log emails instead of sending them.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"os"
+	"strings"
+)
+
+// Message is a plain text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var (
+	Mail Mailer
+
+	// AppURL is the address of the web app that links in emails point to.
+	// Emails contain no links while it is empty.
+	AppURL string
+)
+
+const (
+	BackendLog  = "log"
+	BackendSMTP = "smtp"
+)
+
+const defaultSMTPPort = "587"
+
+type Config struct {
+	Backend string
+	From    string
+	AppURL  string
+	SMTP    SMTPConfig
+}
+
+// ConfigFromEnv reads MAIL_BACKEND ("log" or "smtp"), MAIL_FROM, APP_URL and
+// the SMTP_* variables for the smtp backend, falling back to logging emails
+// when unset.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Backend: BackendLog,
+		From:    os.Getenv("MAIL_FROM"),
+		AppURL:  strings.TrimRight(os.Getenv("APP_URL"), "/"),
+		SMTP: SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     defaultSMTPPort,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		},
+	}
+
+	if value := os.Getenv("MAIL_BACKEND"); value != "" {
+		cfg.Backend = value
+	}
+	if value := os.Getenv("SMTP_PORT"); value != "" {
+		cfg.SMTP.Port = value
+	}
+
+	if cfg.Backend == BackendSMTP && (cfg.SMTP.Host == "" || cfg.From == "") {
+		return cfg, fmt.Errorf("the smtp mail backend needs SMTP_HOST and MAIL_FROM")
+	}
+	return cfg, nil
+}
+
+// Setup makes the configured backend available as Mail.
+func Setup(cfg Config) error {
+	switch cfg.Backend {
+	case BackendLog:
+		Mail = LogMailer{}
+	case BackendSMTP:
+		Mail = NewSMTPMailer(cfg.From, cfg.SMTP)
+	default:
+		return fmt.Errorf("unsupported mail backend %q", cfg.Backend)
+	}
+
+	AppURL = cfg.AppURL
+	return nil
+}
+
+// LogMailer writes emails to the log instead of sending them. It is meant for
+// development, where the log is the only place to pick up invitation tokens.
+type LogMailer struct{}
+
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	logrus.WithFields(logrus.Fields{
+		"to":      msg.To,
+		"subject": msg.Subject,
+	}).Info(msg.Body)
+	return nil
+}
@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"Todo/database/dbHelper"
+	"Todo/models"
+	"Todo/utils"
+	"context"
+	"errors"
+	"net/http"
+)
+
+const (
+	workspaceContext ContextKeys = "workspaceContext"
+
+	// WorkspaceHeader selects the workspace a request works in.
+	WorkspaceHeader = "X-Workspace-Id"
+)
+
+// SelectWorkspace puts the workspace named by the X-Workspace-Id header into
+// the request context once the user turns out to be a member of it. It has to
+// run after Authenticate. Requests without the header work on personal
+// projects.
+func SelectWorkspace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		workspaceID := r.Header.Get(WorkspaceHeader)
+		if workspaceID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user := UserContext(r)
+		role, err := dbHelper.GetWorkspaceRole(workspaceID, user.UserID)
+		if err != nil {
+			switch {
+			case errors.Is(err, dbHelper.ErrInvalidUUID):
+				utils.RespondError(w, http.StatusBadRequest, err, "invalid workspace id")
+			case errors.Is(err, dbHelper.ErrNotFound):
+				utils.RespondError(w, http.StatusNotFound, err, "workspace not found")
+			default:
+				utils.RespondError(w, http.StatusInternalServerError, err, "internal server error")
+			}
+			return
+		}
+
+		workspace := &models.WorkspaceCtx{
+			WorkspaceID: workspaceID,
+			Role:        role,
+		}
+
+		ctx := context.WithValue(r.Context(), workspaceContext, workspace)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WorkspaceContext returns the selected workspace, or nil for requests on
+// personal projects.
+func WorkspaceContext(r *http.Request) *models.WorkspaceCtx {
+	if workspace, ok := r.Context().Value(workspaceContext).(*models.WorkspaceCtx); ok {
+		return workspace
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ActivityEntity string
+
+const (
+	ActivityEntityTodo       ActivityEntity = "todo"
+	ActivityEntitySession    ActivityEntity = "session"
+	ActivityEntityUser       ActivityEntity = "user"
+	ActivityEntityProject    ActivityEntity = "project"
+	ActivityEntityTag        ActivityEntity = "tag"
+	ActivityEntityComment    ActivityEntity = "comment"
+	ActivityEntityAttachment ActivityEntity = "attachment"
+	ActivityEntityShare      ActivityEntity = "share"
+	ActivityEntityWorkspace  ActivityEntity = "workspace"
+	ActivityEntityMember     ActivityEntity = "member"
+	ActivityEntityInvitation ActivityEntity = "invitation"
+)
+
+type ActivityAction string
+
+const (
+	ActivityCreate   ActivityAction = "create"
+	ActivityUpdate   ActivityAction = "update"
+	ActivityComplete ActivityAction = "complete"
+	ActivityReopen   ActivityAction = "reopen"
+	ActivityDelete   ActivityAction = "delete"
+	ActivityRestore  ActivityAction = "restore"
+	ActivityPurge    ActivityAction = "purge"
+	ActivityLogin    ActivityAction = "login"
+	ActivityLogout   ActivityAction = "logout"
+	ActivityTag      ActivityAction = "tag"
+	ActivityUntag    ActivityAction = "untag"
+	ActivityBlock    ActivityAction = "block"
+	ActivityUnblock  ActivityAction = "unblock"
+)
+
+// Activity is an entry of the activity log. Before and After hold the JSON
+// form of the entity around the change, when there is one. For tagging and
+// blocking a todo they hold the IDs of the tags or blockers removed or added.
+type Activity struct {
+	ID        string           `json:"id" db:"id"`
+	ActorID   string           `json:"actorId" db:"actor_id"`
+	ActorName string           `json:"actorName" db:"actor_name"`
+	Entity    ActivityEntity   `json:"entity" db:"entity"`
+	EntityID  *string          `json:"entityId" db:"entity_id"`
+	Action    ActivityAction   `json:"action" db:"action"`
+	Before    *json.RawMessage `json:"before" db:"before"`
+	After     *json.RawMessage `json:"after" db:"after"`
+	CreatedAt time.Time        `json:"createdAt" db:"created_at"`
+}
+
+type ActivityFilters struct {
+	TodoID string
+	Entity string
+	Action string
+	Limit  int
+	Cursor *ActivityCursor
+}
+
+// ActivityCursor marks the last entry of a page of the activity log, which is
+// ordered from the newest entry to the oldest.
+type ActivityCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+type ActivityPage struct {
+	Items      []Activity `json:"items"`
+	NextCursor string     `json:"nextCursor"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type Attachment struct {
+	ID          string    `json:"id" db:"id"`
+	TodoID      string    `json:"todoId" db:"todo_id"`
+	FileName    string    `json:"fileName" db:"file_name"`
+	ContentType string    `json:"contentType" db:"content_type"`
+	Size        int64     `json:"size" db:"size"`
+	StorageKey  string    `json:"-" db:"storage_key"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// Board shows the todos of a workflow as one column per status.
+type Board struct {
+	ProjectID *string       `json:"projectId"`
+	Columns   []BoardColumn `json:"columns"`
+}
+
+// BoardColumn is a status with the todos in it. Count is the number of todos
+// in the column on this board while AtLimit tells whether the status has
+// reached its WIP limit, which counts the todos of every project sharing the
+// workflow.
+type BoardColumn struct {
+	Status
+	Count   int    `json:"count" db:"count"`
+	AtLimit bool   `json:"atLimit" db:"at_limit"`
+	Todos   []Todo `json:"todos" db:"-"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import "encoding/json"
+
+type BulkMode string
+
+const (
+	BulkModeAtomic     BulkMode = "atomic"
+	BulkModeBestEffort BulkMode = "best_effort"
+)
+
+type BulkOperationType string
+
+const (
+	BulkOperationCreate   BulkOperationType = "create"
+	BulkOperationUpdate   BulkOperationType = "update"
+	BulkOperationComplete BulkOperationType = "complete"
+	BulkOperationDelete   BulkOperationType = "delete"
+	BulkOperationMove     BulkOperationType = "move"
+)
+
+type BulkResultStatus string
+
+const (
+	BulkResultOK         BulkResultStatus = "ok"
+	BulkResultFailed     BulkResultStatus = "failed"
+	BulkResultSkipped    BulkResultStatus = "skipped"
+	BulkResultRolledBack BulkResultStatus = "rolled_back"
+)
+
+type BulkTodoRequest struct {
+	Mode       BulkMode            `json:"mode" validate:"omitempty,oneof=atomic best_effort"`
+	Operations []BulkTodoOperation `json:"operations" validate:"required,min=1,max=100,dive"`
+}
+
+// BulkTodoOperation is a single item of a bulk request. Which of the fields
+// besides Op are used depends on the operation: create takes Todo, update
+// takes a merge patch, move takes ProjectID and complete takes Cascade and
+// Force.
+type BulkTodoOperation struct {
+	Op        BulkOperationType `json:"op" validate:"required,oneof=create update complete delete move"`
+	TodoID    string            `json:"todoId" validate:"required_unless=Op create,omitempty,uuid"`
+	Todo      *TodoRequest      `json:"todo" validate:"required_if=Op create"`
+	Patch     json.RawMessage   `json:"patch" validate:"required_if=Op update"`
+	ProjectID string            `json:"projectId" validate:"required_if=Op move,omitempty,uuid"`
+	Cascade   bool              `json:"cascade"`
+	Force     bool              `json:"force"`
+}
+
+type BulkTodoResult struct {
+	Index      int               `json:"index"`
+	Op         BulkOperationType `json:"op"`
+	Status     BulkResultStatus  `json:"status"`
+	TodoID     string            `json:"todoId,omitempty"`
+	StatusCode int               `json:"statusCode,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+type BulkTodoResponse struct {
+	Committed bool             `json:"committed"`
+	Results   []BulkTodoResult `json:"results"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type CommentRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+type Comment struct {
+	ID         string     `json:"id" db:"id"`
+	TodoID     string     `json:"todoId" db:"todo_id"`
+	AuthorID   string     `json:"authorId" db:"user_id"`
+	AuthorName string     `json:"authorName" db:"author_name"`
+	Body       string     `json:"body" db:"body"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	EditedAt   *time.Time `json:"editedAt" db:"edited_at"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type SavedFilterRequest struct {
+	Name     string         `json:"name" validate:"required"`
+	Criteria FilterCriteria `json:"criteria"`
+}
+
+// FilterCriteria is the stored form of the query parameters of
+// GET /v1/todo/, using the same names and formats. Pagination is not part of
+// a saved filter.
+type FilterCriteria struct {
+	Keyword          string     `json:"keyword,omitempty"`
+	Query            string     `json:"q,omitempty"`
+	Completed        *bool      `json:"completed,omitempty"`
+	ProjectID        string     `json:"project,omitempty" validate:"omitempty,uuid"`
+	DueBefore        *time.Time `json:"due_before,omitempty"`
+	DueAfter         *time.Time `json:"due_after,omitempty"`
+	CompletedBefore  *time.Time `json:"completed_before,omitempty"`
+	CompletedAfter   *time.Time `json:"completed_after,omitempty"`
+	Overdue          bool       `json:"overdue,omitempty"`
+	IncludeUnstarted bool       `json:"include_unstarted,omitempty"`
+	IncludeSubtasks  bool       `json:"include_subtasks,omitempty"`
+	Tags             []string   `json:"tag,omitempty" validate:"dive,required"`
+	TagMode          string     `json:"tag_mode,omitempty" validate:"omitempty,oneof=any all"`
+	Assignee         string     `json:"assignee,omitempty"`
+	Sort             string     `json:"sort,omitempty"`
+}
+
+func (c FilterCriteria) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *FilterCriteria) Scan(src interface{}) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return errors.New("filter criteria must be read from JSONB")
+	}
+	return json.Unmarshal(data, c)
+}
+
+type SavedFilter struct {
+	ID        string         `json:"id" db:"id"`
+	Name      string         `json:"name" db:"name"`
+	Criteria  FilterCriteria `json:"criteria" db:"criteria"`
+	CreatedAt time.Time      `json:"createdAt" db:"created_at"`
+}
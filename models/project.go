@@ -0,0 +1,20 @@
+package models
+
+type ProjectRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type Project struct {
+	ID          string  `json:"id" db:"id"`
+	Name        string  `json:"name" db:"name"`
+	IsInbox     bool    `json:"isInbox" db:"is_inbox"`
+	WorkspaceID *string `json:"workspaceId" db:"workspace_id"`
+}
+
+// ProjectAccess is what a user may do with the todos of a project. Todos
+// created in the project belong to OwnerID no matter who creates them.
+type ProjectAccess struct {
+	OwnerID     string  `db:"user_id"`
+	WorkspaceID *string `db:"workspace_id"`
+	Role        Role    `db:"-"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Role is the access a user has to a todo. Owners and editors may change it,
+// viewers only read it.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+func (r Role) CanEdit() bool {
+	return r == RoleOwner || r == RoleEditor
+}
+
+// ShareTarget is the kind of item a share grants access to.
+type ShareTarget string
+
+const (
+	ShareTargetTodo    ShareTarget = "todo"
+	ShareTargetProject ShareTarget = "project"
+)
+
+type ShareRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  Role   `json:"role" validate:"required,oneof=viewer editor"`
+}
+
+type UpdateShareRequest struct {
+	Role Role `json:"role" validate:"required,oneof=viewer editor"`
+}
+
+type Share struct {
+	ID        string    `json:"id" db:"id"`
+	TodoID    *string   `json:"todoId" db:"todo_id"`
+	ProjectID *string   `json:"projectId" db:"project_id"`
+	UserID    string    `json:"userId" db:"user_id"`
+	UserName  string    `json:"userName" db:"user_name"`
+	UserEmail string    `json:"userEmail" db:"user_email"`
+	Role      Role      `json:"role" db:"role"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
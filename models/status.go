@@ -0,0 +1,43 @@
+package models
+
+type StatusCategory string
+
+const (
+	StatusCategoryTodo  StatusCategory = "todo"
+	StatusCategoryDoing StatusCategory = "doing"
+	StatusCategoryDone  StatusCategory = "done"
+)
+
+type StatusRequest struct {
+	ProjectID string         `json:"projectId" validate:"omitempty,uuid"`
+	Name      string         `json:"name" validate:"required"`
+	Category  StatusCategory `json:"category" validate:"required,oneof=todo doing done"`
+	WIPLimit  *int           `json:"wipLimit" validate:"omitempty,min=1"`
+}
+
+type UpdateStatusRequest struct {
+	Name     string         `json:"name" validate:"required"`
+	Category StatusCategory `json:"category" validate:"required,oneof=todo doing done"`
+	WIPLimit *int           `json:"wipLimit" validate:"omitempty,min=1"`
+}
+
+type StatusOrderRequest struct {
+	ProjectID string   `json:"projectId" validate:"omitempty,uuid"`
+	StatusIDs []string `json:"statusIds" validate:"required,min=1,dive,uuid"`
+}
+
+type TodoStatusRequest struct {
+	StatusID string `json:"statusId" validate:"required,uuid"`
+}
+
+// Status is a step of a workflow. Statuses without a project make up the
+// user's default workflow, the others belong to a project with a workflow of
+// its own.
+type Status struct {
+	ID        string         `json:"id" db:"id"`
+	ProjectID *string        `json:"projectId" db:"project_id"`
+	Name      string         `json:"name" db:"name"`
+	Category  StatusCategory `json:"category" db:"category"`
+	Position  int            `json:"position" db:"position"`
+	WIPLimit  *int           `json:"wipLimit" db:"wip_limit"`
+}
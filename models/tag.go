@@ -0,0 +1,14 @@
+package models
+
+type TagRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type TodoTagsRequest struct {
+	TagIDs []string `json:"tagIds" validate:"required,min=1,dive,uuid"`
+}
+
+type Tag struct {
+	ID   string `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+}
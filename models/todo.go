@@ -1,15 +1,170 @@
 package models
 
+import "time"
+
+type Priority string
+
+const (
+	PriorityNone   Priority = "none"
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+type TodoSortKey string
+
+const (
+	TodoSortPriority  TodoSortKey = "priority"
+	TodoSortDueAt     TodoSortKey = "due_at"
+	TodoSortCreatedAt TodoSortKey = "created_at"
+	TodoSortName      TodoSortKey = "name"
+	TodoSortPosition  TodoSortKey = "position"
+	TodoSortRelevance TodoSortKey = "relevance"
+)
+
+// AssigneeMe and AssigneeNone are the special values of the assignee filter
+// next to the id of a user.
+const (
+	AssigneeMe   = "me"
+	AssigneeNone = "none"
+)
+
+type TodoSort struct {
+	Key  TodoSortKey
+	Desc bool
+}
+
 type TodoRequest struct {
-	UserID      string `json:"user_id"`
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description" validate:"required"`
+	UserID      string     `json:"user_id"`
+	ProjectID   string     `json:"projectId" validate:"omitempty,uuid"`
+	ParentID    string     `json:"-"`
+	Name        string     `json:"name" validate:"required"`
+	Description string     `json:"description" validate:"required"`
+	DueAt       *time.Time `json:"dueAt"`
+	StartAt     *time.Time `json:"startAt"`
+	Priority    Priority   `json:"priority" validate:"omitempty,oneof=none low medium high urgent"`
+
+	RecurrenceRule     string `json:"recurrenceRule"`
+	RecurrenceTimezone string `json:"recurrenceTimezone"`
+}
+
+type UpdateTodoRequest struct {
+	Name         *string    `json:"name" validate:"omitempty,min=1"`
+	Description  *string    `json:"description" validate:"omitempty,min=1"`
+	IsCompleted  *bool      `json:"isCompleted"`
+	DueAt        *time.Time `json:"dueAt"`
+	StartAt      *time.Time `json:"startAt"`
+	Priority     *Priority  `json:"priority" validate:"omitempty,oneof=none low medium high urgent"`
+	ProjectID    *string    `json:"projectId" validate:"omitempty,uuid"`
+	ClearDueAt   bool       `json:"-"`
+	ClearStartAt bool       `json:"-"`
+
+	RecurrenceRule     *string `json:"recurrenceRule"`
+	RecurrenceTimezone *string `json:"recurrenceTimezone"`
+	ClearRecurrence    bool    `json:"-"`
+}
+
+// AssignTodoRequest assigns a todo to a user, or unassigns it when AssigneeID
+// is null.
+type AssignTodoRequest struct {
+	AssigneeID *string `json:"assigneeId" validate:"omitempty,uuid"`
+}
+
+type MoveTodoRequest struct {
+	Before string `json:"before" validate:"required_without=After,omitempty,uuid"`
+	After  string `json:"after" validate:"required_without=Before,omitempty,uuid"`
+}
+
+type TodoFilters struct {
+	Keyword          string
+	Query            string
+	ProjectID        string
+	Completed        string
+	DueBefore        *time.Time
+	DueAfter         *time.Time
+	CompletedBefore  *time.Time
+	CompletedAfter   *time.Time
+	Overdue          bool
+	IncludeUnstarted bool
+	IncludeSubtasks  bool
+	Tags             []string
+	MatchAllTags     bool
+	Assignee         string
+	Sort             []TodoSort
+	Limit            int
+	Cursor           *TodoCursor
+}
+
+// TodoCursor marks the last row of a page. It is handed to clients as an
+// opaque token and only valid for the sort order it was produced with.
+type TodoCursor struct {
+	Sort   string    `json:"s"`
+	Values []*string `json:"v"`
+	ID     string    `json:"id"`
+}
+
+type TodoPage struct {
+	Items      []Todo `json:"items"`
+	NextCursor string `json:"nextCursor"`
 }
 
 type Todo struct {
+	ID          string     `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Description string     `json:"description" db:"description"`
+	IsCompleted bool       `json:"isCompleted" db:"is_completed"`
+	CompletedAt *time.Time `json:"completedAt" db:"completed_at"`
+	UserID      string     `json:"userId" db:"user_id"`
+	ProjectID   string     `json:"projectId" db:"project_id"`
+	ParentID    *string    `json:"parentId" db:"parent_id"`
+	AssigneeID  *string    `json:"assigneeId" db:"assignee_id"`
+	DueAt       *time.Time `json:"dueAt" db:"due_at"`
+	StartAt     *time.Time `json:"startAt" db:"start_at"`
+	Priority    Priority   `json:"priority" db:"priority"`
+	Position    string     `json:"position" db:"position"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	ArchivedAt  *time.Time `json:"archivedAt,omitempty" db:"archived_at"`
+	Tags        []Tag      `json:"tags" db:"-"`
+	BlockedBy   []TodoLink `json:"blockedBy" db:"-"`
+	Blocks      []TodoLink `json:"blocks" db:"-"`
+
+	AssigneeName *string `json:"assigneeName" db:"assignee_name"`
+
+	StatusID       string         `json:"statusId" db:"status_id"`
+	StatusName     string         `json:"statusName" db:"status_name"`
+	StatusCategory StatusCategory `json:"statusCategory" db:"status_category"`
+
+	RecurrenceRule     *string    `json:"recurrenceRule" db:"recurrence_rule"`
+	RecurrenceTimezone *string    `json:"recurrenceTimezone" db:"recurrence_timezone"`
+	RecurrenceStart    *time.Time `json:"-" db:"recurrence_start"`
+	RecurrenceIndex    int        `json:"-" db:"recurrence_index"`
+
+	SubtasksDone  int    `json:"subtasksDone" db:"subtasks_done"`
+	SubtasksTotal int    `json:"subtasksTotal" db:"subtasks_total"`
+	Subtasks      []Todo `json:"subtasks,omitempty" db:"-"`
+
+	CommentsCount int `json:"commentsCount" db:"comments_count"`
+
+	Rank      *float32       `json:"rank,omitempty" db:"rank"`
+	Highlight *TodoHighlight `json:"highlight,omitempty" db:"-"`
+}
+
+// TodoLink is the short form of a todo shown on the other end of a
+// dependency.
+type TodoLink struct {
 	ID          string `json:"id" db:"id"`
 	Name        string `json:"name" db:"name"`
-	Description string `json:"description" db:"description"`
 	IsCompleted bool   `json:"isCompleted" db:"is_completed"`
-	UserID      string `json:"userId" db:"user_id"`
+}
+
+type TodoBlockersRequest struct {
+	BlockerIDs []string `json:"blockerIds" validate:"required,min=1,dive,uuid"`
+}
+
+// TodoHighlight holds the name and description of a todo found by a full-text
+// search with the matching words wrapped in <mark> tags.
+type TodoHighlight struct {
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
 }
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// WorkspaceRole is the role of a member in a workspace. Owners and admins
+// manage the workspace and its members, members work on its todos and guests
+// only read them.
+type WorkspaceRole string
+
+const (
+	WorkspaceRoleOwner  WorkspaceRole = "owner"
+	WorkspaceRoleAdmin  WorkspaceRole = "admin"
+	WorkspaceRoleMember WorkspaceRole = "member"
+	WorkspaceRoleGuest  WorkspaceRole = "guest"
+)
+
+func (r WorkspaceRole) CanManage() bool {
+	return r == WorkspaceRoleOwner || r == WorkspaceRoleAdmin
+}
+
+func (r WorkspaceRole) CanEdit() bool {
+	return r != WorkspaceRoleGuest
+}
+
+// WorkspaceCtx is the workspace a request works in, selected with the
+// X-Workspace-Id header. Requests without it work on personal projects.
+type WorkspaceCtx struct {
+	WorkspaceID string        `json:"workspaceId"`
+	Role        WorkspaceRole `json:"role"`
+}
+
+type WorkspaceRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type Workspace struct {
+	ID        string        `json:"id" db:"id"`
+	Name      string        `json:"name" db:"name"`
+	Role      WorkspaceRole `json:"role" db:"role"`
+	CreatedAt time.Time     `json:"createdAt" db:"created_at"`
+}
+
+type WorkspaceMemberRequest struct {
+	Role WorkspaceRole `json:"role" validate:"required,oneof=admin member guest"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID string        `json:"workspaceId" db:"workspace_id"`
+	UserID      string        `json:"userId" db:"user_id"`
+	Name        string        `json:"name" db:"name"`
+	Email       string        `json:"email" db:"email"`
+	Role        WorkspaceRole `json:"role" db:"role"`
+	JoinedAt    time.Time     `json:"joinedAt" db:"created_at"`
+}
+
+type InvitationRequest struct {
+	Email string        `json:"email" validate:"required,email"`
+	Role  WorkspaceRole `json:"role" validate:"required,oneof=admin member guest"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type Invitation struct {
+	ID          string        `json:"id" db:"id"`
+	WorkspaceID string        `json:"workspaceId" db:"workspace_id"`
+	Email       string        `json:"email" db:"email"`
+	Role        WorkspaceRole `json:"role" db:"role"`
+	InvitedBy   string        `json:"invitedBy" db:"invited_by"`
+	CreatedAt   time.Time     `json:"createdAt" db:"created_at"`
+	ExpiresAt   time.Time     `json:"expiresAt" db:"expires_at"`
+}
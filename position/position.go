@@ -0,0 +1,154 @@
+// Package position generates lexicographic sort keys for manually ordered
+// lists. A key is read as a base-62 fraction between 0 and 1, so a new key
+// fits between any two distinct keys and moving an item rewrites only that
+// item. Keys compare with plain byte order, which is what Go string
+// comparison and the "C" collation in Postgres use.
+package position
+
+import (
+	"errors"
+	"strings"
+)
+
+const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+const base = len(digits)
+
+// MaxKeyLength is the longest key a list should hold. Repeated moves into the
+// same gap grow keys by about a digit per six moves, and lists with longer
+// keys are due for a rebalance with Spread.
+const MaxKeyLength = 16
+
+var ErrInvalidRange = errors.New("lower key must sort before upper key")
+
+var ErrInvalidKey = errors.New("invalid position key")
+
+// Between returns a key that sorts strictly between lower and upper. An empty
+// lower means the start of the list, an empty upper its end.
+func Between(lower, upper string) (string, error) {
+	if !valid(lower) || !valid(upper) {
+		return "", ErrInvalidKey
+	}
+	if upper != "" && lower >= upper {
+		return "", ErrInvalidRange
+	}
+
+	switch {
+	case lower != "" && upper == "":
+		return after(lower), nil
+	case lower == "" && upper != "":
+		return before(upper), nil
+	default:
+		return midpoint(lower, upper), nil
+	}
+}
+
+// Spread returns n keys in ascending order that are spaced evenly over the
+// whole key range, all of the same short length. It is used to rebalance a
+// list whose keys grew long after many moves into the same gap.
+func Spread(n int) []string {
+	keys := make([]string, 0, n)
+	if n <= 0 {
+		return keys
+	}
+
+	length, span := 1, uint64(base)
+	for span < uint64(n)+1 {
+		length++
+		span *= uint64(base)
+	}
+	step := span / (uint64(n) + 1)
+
+	for i := 1; i <= n; i++ {
+		keys = append(keys, encode(uint64(i)*step, length))
+	}
+	return keys
+}
+
+// midpoint finds the shortest key between lower and upper, where upper may be
+// empty to stand for 1. Both keys are free of trailing zero digits, so
+// every fraction has exactly one key.
+func midpoint(lower, upper string) string {
+	if upper != "" {
+		n := 0
+		for n < len(upper) && digitAt(lower, n) == index(upper[n]) {
+			n++
+		}
+		if n > 0 {
+			return upper[:n] + midpoint(suffix(lower, n), upper[n:])
+		}
+	}
+
+	lowDigit := digitAt(lower, 0)
+	highDigit := base
+	if upper != "" {
+		highDigit = index(upper[0])
+	}
+
+	if highDigit-lowDigit > 1 {
+		return string(digits[(lowDigit+highDigit+1)/2])
+	}
+	if len(upper) > 1 {
+		return upper[:1]
+	}
+	return string(digits[lowDigit]) + midpoint(suffix(lower, 1), "")
+}
+
+// after steps past the end of a list by bumping the first digit of lower
+// that has room, so that appending keeps keys short instead of halving the
+// remaining range every time.
+func after(lower string) string {
+	for i := 0; i < len(lower); i++ {
+		if d := index(lower[i]); d < base-1 {
+			return lower[:i] + string(digits[d+1])
+		}
+	}
+	return lower + string(digits[1])
+}
+
+// before is the counterpart of after for the start of a list. A digit is
+// only lowered if that does not leave a trailing zero.
+func before(upper string) string {
+	for i := 0; i < len(upper); i++ {
+		if d := index(upper[i]); d > 1 {
+			return upper[:i] + string(digits[d-1])
+		}
+	}
+	return midpoint("", upper)
+}
+
+func encode(value uint64, length int) string {
+	key := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		key[i] = digits[value%uint64(base)]
+		value /= uint64(base)
+	}
+	return strings.TrimRight(string(key), digits[:1])
+}
+
+func valid(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if index(key[i]) < 0 {
+			return false
+		}
+	}
+	return !strings.HasSuffix(key, digits[:1])
+}
+
+func digitAt(key string, i int) int {
+	if i >= len(key) {
+		return 0
+	}
+	return index(key[i])
+}
+
+func suffix(key string, n int) string {
+	if n >= len(key) {
+		return ""
+	}
+	return key[n:]
+}
+
+func index(c byte) int {
+	return strings.IndexByte(digits, c)
+}
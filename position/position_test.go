@@ -0,0 +1,206 @@
+package position
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name         string
+		lower, upper string
+	}{
+		{"empty list", "", ""},
+		{"start of list", "", "V"},
+		{"end of list", "V", ""},
+		{"adjacent digits", "A", "B"},
+		{"adjacent with shared prefix", "AB", "AC"},
+		{"prefix of upper", "A", "A1"},
+		{"prefix of lower", "A1", "B"},
+		{"wide gap", "1", "z"},
+		{"before the smallest digit", "", "1"},
+		{"before a key of leading zeros", "", "001"},
+		{"after the largest digit", "z", ""},
+		{"after a key of largest digits", "zzz", ""},
+		{"between largest digits", "zy", "zz"},
+		{"long keys", "Vzzzzzzz", "W"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := Between(tt.lower, tt.upper)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !valid(key) || key == "" {
+				t.Fatalf("invalid key %q", key)
+			}
+			if key <= tt.lower {
+				t.Fatalf("key %q does not sort after %q", key, tt.lower)
+			}
+			if tt.upper != "" && key >= tt.upper {
+				t.Fatalf("key %q does not sort before %q", key, tt.upper)
+			}
+		})
+	}
+}
+
+func TestBetweenErrors(t *testing.T) {
+	tests := []struct {
+		lower, upper string
+		want         error
+	}{
+		{"A", "A", ErrInvalidRange},
+		{"B", "A", ErrInvalidRange},
+		{"A1", "A", ErrInvalidRange},
+		{"A0", "", ErrInvalidKey},
+		{"", "0", ErrInvalidKey},
+		{"A-", "", ErrInvalidKey},
+		{"", "é", ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		if _, err := Between(tt.lower, tt.upper); !errors.Is(err, tt.want) {
+			t.Errorf("Between(%q, %q): got %v, want %v", tt.lower, tt.upper, err, tt.want)
+		}
+	}
+}
+
+// TestBetweenAtTheEnds checks that keys at the ends of a list gain a digit
+// only every few dozen keys: appends step up through all digits before adding
+// one, prepends step down from the middle digit.
+func TestBetweenAtTheEnds(t *testing.T) {
+	const n = 1000
+
+	key := ""
+	for i := 0; i < n; i++ {
+		next, err := Between(key, "")
+		if err != nil {
+			t.Fatalf("append %d: %v", i, err)
+		}
+		if next <= key {
+			t.Fatalf("append %d: %q does not sort after %q", i, next, key)
+		}
+		key = next
+	}
+	if max := 1 + n/(base-2); len(key) > max {
+		t.Errorf("%d appends grew the key to %d digits, want at most %d", n, len(key), max)
+	}
+
+	key = ""
+	for i := 0; i < n; i++ {
+		next, err := Between("", key)
+		if err != nil {
+			t.Fatalf("prepend %d: %v", i, err)
+		}
+		if key != "" && next >= key {
+			t.Fatalf("prepend %d: %q does not sort before %q", i, next, key)
+		}
+		key = next
+	}
+	if max := 1 + n/(base/2-1); len(key) > max {
+		t.Errorf("%d prepends grew the key to %d digits, want at most %d", n, len(key), max)
+	}
+}
+
+// TestRandomInserts checks the order invariant against a list that is built
+// from inserts at random places.
+func TestRandomInserts(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var keys []string
+
+	for i := 0; i < 2000; i++ {
+		at := rng.Intn(len(keys) + 1)
+		lower, upper := "", ""
+		if at > 0 {
+			lower = keys[at-1]
+		}
+		if at < len(keys) {
+			upper = keys[at]
+		}
+
+		key, err := Between(lower, upper)
+		if err != nil {
+			t.Fatalf("insert %d between %q and %q: %v", i, lower, upper, err)
+		}
+		keys = append(keys[:at], append([]string{key}, keys[at:]...)...)
+	}
+
+	for i := 1; i < len(keys); i++ {
+		if keys[i-1] >= keys[i] {
+			t.Fatalf("keys %q and %q are out of order", keys[i-1], keys[i])
+		}
+	}
+}
+
+func TestSpread(t *testing.T) {
+	for _, n := range []int{1, 2, 61, 62, 63, 3843, 3844, 100000} {
+		keys := Spread(n)
+		if len(keys) != n {
+			t.Fatalf("Spread(%d) returned %d keys", n, len(keys))
+		}
+
+		// byte order is what COLLATE "C" sorts by
+		if !sort.StringsAreSorted(keys) {
+			t.Fatalf("Spread(%d) is not sorted", n)
+		}
+		for i, key := range keys {
+			if key == "" || !valid(key) {
+				t.Fatalf("Spread(%d) returned invalid key %q", n, key)
+			}
+			if i > 0 && keys[i-1] >= key {
+				t.Fatalf("Spread(%d) is not strictly increasing at %q, %q", n, keys[i-1], key)
+			}
+		}
+	}
+
+	if keys := Spread(0); len(keys) != 0 {
+		t.Errorf("Spread(0) returned %v", keys)
+	}
+}
+
+func TestSpreadLeavesRoomBelowThreshold(t *testing.T) {
+	keys := Spread(100000)
+	for _, key := range keys {
+		if len(key) > 3 {
+			t.Fatalf("Spread(100000) returned %q, longer than 3 digits", key)
+		}
+	}
+
+	// a rebalanced list takes new keys at both ends and in between without
+	// getting anywhere near the threshold
+	for _, pair := range [][2]string{{"", keys[0]}, {keys[0], keys[1]}, {keys[len(keys)-1], ""}} {
+		key, err := Between(pair[0], pair[1])
+		if err != nil {
+			t.Fatalf("Between(%q, %q): %v", pair[0], pair[1], err)
+		}
+		if len(key) > MaxKeyLength/2 {
+			t.Errorf("Between(%q, %q) = %q after a rebalance", pair[0], pair[1], key)
+		}
+	}
+}
+
+// TestRepeatedMovesReachThreshold moves items into the same gap over and over,
+// the worst case for key length, and checks that it takes many moves before
+// keys outgrow MaxKeyLength and call for a rebalance.
+func TestRepeatedMovesReachThreshold(t *testing.T) {
+	lower, upper := "V", "W"
+	moves := 0
+	for len(upper) <= MaxKeyLength {
+		key, err := Between(lower, upper)
+		if err != nil {
+			t.Fatalf("move %d: %v", moves, err)
+		}
+		upper = key
+		moves++
+	}
+
+	if moves < 5*MaxKeyLength {
+		t.Errorf("keys outgrew %d digits after only %d moves", MaxKeyLength, moves)
+	}
+	if moves > 7*MaxKeyLength {
+		t.Errorf("keys took %d moves to outgrow %d digits, longer than the threshold assumes", moves, MaxKeyLength)
+	}
+}
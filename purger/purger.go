@@ -0,0 +1,179 @@
+package purger
+
+import (
+	"Todo/database/dbHelper"
+	"Todo/storage"
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetention = 30 * 24 * time.Hour
+	defaultInterval  = time.Hour
+	defaultBatchSize = 500
+)
+
+type Config struct {
+	Retention time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
+
+// ConfigFromEnv reads PURGE_RETENTION and PURGE_INTERVAL as Go durations and
+// PURGE_BATCH_SIZE as a row count, falling back to defaults when unset.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Retention: defaultRetention,
+		Interval:  defaultInterval,
+		BatchSize: defaultBatchSize,
+	}
+
+	for env, target := range map[string]*time.Duration{
+		"PURGE_RETENTION": &cfg.Retention,
+		"PURGE_INTERVAL":  &cfg.Interval,
+	} {
+		if value := os.Getenv(env); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return cfg, fmt.Errorf("%s must be a positive duration, got %q", env, value)
+			}
+			*target = d
+		}
+	}
+
+	if value := os.Getenv("PURGE_BATCH_SIZE"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("PURGE_BATCH_SIZE must be a positive integer, got %q", value)
+		}
+		cfg.BatchSize = n
+	}
+
+	return cfg, nil
+}
+
+type Result struct {
+	Todos       int64
+	Sessions    int64
+	Attachments int64
+	Blobs       int64
+}
+
+type Purger struct {
+	cfg   Config
+	blobs storage.BlobStore
+}
+
+// New returns a purger that also removes the blobs of purged attachments from
+// blobs.
+func New(cfg Config, blobs storage.BlobStore) *Purger {
+	return &Purger{cfg: cfg, blobs: blobs}
+}
+
+// Run purges once right away and then on every interval until ctx is
+// cancelled. A batch that has started is always finished, so cancelling only
+// waits for at most one batch.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := p.PurgeOnce(ctx)
+		if err != nil {
+			logrus.WithError(err).Error("failed to purge archived rows")
+		}
+		if result.Todos > 0 || result.Sessions > 0 || result.Attachments > 0 || result.Blobs > 0 {
+			logrus.WithFields(logrus.Fields{
+				"todos":       result.Todos,
+				"sessions":    result.Sessions,
+				"attachments": result.Attachments,
+				"blobs":       result.Blobs,
+			}).Info("purged archived rows")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOnce deletes everything archived before the retention window, batch by
+// batch, and reports how many rows went.
+func (p *Purger) PurgeOnce(ctx context.Context) (Result, error) {
+	var result Result
+	cutoff := time.Now().Add(-p.cfg.Retention)
+
+	todos, err := p.purge(ctx, func() (int64, error) {
+		return dbHelper.PurgeArchivedTodos(cutoff, p.cfg.BatchSize)
+	})
+	result.Todos = todos
+	if err != nil {
+		return result, fmt.Errorf("purging todos: %w", err)
+	}
+
+	sessions, err := p.purge(ctx, func() (int64, error) {
+		return dbHelper.PurgeArchivedSessions(cutoff, p.cfg.BatchSize)
+	})
+	result.Sessions = sessions
+	if err != nil {
+		return result, fmt.Errorf("purging sessions: %w", err)
+	}
+
+	attachments, err := p.purge(ctx, func() (int64, error) {
+		return dbHelper.PurgeArchivedAttachments(cutoff, p.cfg.BatchSize)
+	})
+	result.Attachments = attachments
+	if err != nil {
+		return result, fmt.Errorf("purging attachments: %w", err)
+	}
+
+	// blobs go last so that those of the todos and attachments purged above
+	// are picked up in the same run
+	blobs, err := p.purge(ctx, func() (int64, error) {
+		return p.purgeBlobs(ctx)
+	})
+	result.Blobs = blobs
+	if err != nil {
+		return result, fmt.Errorf("purging blobs: %w", err)
+	}
+
+	return result, nil
+}
+
+// purgeBlobs removes one batch of queued blobs from storage. Keys are only
+// dequeued once their blob is gone, so a failing store is retried next run.
+func (p *Purger) purgeBlobs(ctx context.Context) (int64, error) {
+	keys, err := dbHelper.GetBlobDeletions(p.cfg.BatchSize)
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+
+	for _, key := range keys {
+		if err = p.blobs.Delete(ctx, key); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = dbHelper.RemoveBlobDeletions(keys); err != nil {
+		return 0, err
+	}
+	return int64(len(keys)), nil
+}
+
+func (p *Purger) purge(ctx context.Context, batch func() (int64, error)) (int64, error) {
+	var total int64
+	for ctx.Err() == nil {
+		n, err := batch()
+		total += n
+		if err != nil || n == 0 {
+			return total, err
+		}
+	}
+	return total, nil
+}
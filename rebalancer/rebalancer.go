@@ -0,0 +1,131 @@
+package rebalancer
+
+import (
+	"Todo/database"
+	"Todo/database/dbHelper"
+	"Todo/position"
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultInterval     = time.Hour
+	defaultMaxKeyLength = position.MaxKeyLength
+	defaultBatchSize    = 100
+
+	// minMaxKeyLength is the key length of a rebalanced list of up to
+	// 14 million todos. With a shorter limit freshly spread keys would be
+	// due for a rebalance right away.
+	minMaxKeyLength = 4
+)
+
+type Config struct {
+	Interval     time.Duration
+	MaxKeyLength int
+	BatchSize    int
+}
+
+// ConfigFromEnv reads REBALANCE_INTERVAL as a Go duration,
+// REBALANCE_MAX_KEY_LENGTH as the longest position key left alone and
+// REBALANCE_BATCH_SIZE as the number of users handled per batch, falling back
+// to defaults when unset. The key length has to be at least 4.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Interval:     defaultInterval,
+		MaxKeyLength: defaultMaxKeyLength,
+		BatchSize:    defaultBatchSize,
+	}
+
+	if value := os.Getenv("REBALANCE_INTERVAL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("REBALANCE_INTERVAL must be a positive duration, got %q", value)
+		}
+		cfg.Interval = d
+	}
+
+	for env, target := range map[string]*int{
+		"REBALANCE_MAX_KEY_LENGTH": &cfg.MaxKeyLength,
+		"REBALANCE_BATCH_SIZE":     &cfg.BatchSize,
+	} {
+		if value := os.Getenv(env); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return cfg, fmt.Errorf("%s must be a positive integer, got %q", env, value)
+			}
+			*target = n
+		}
+	}
+
+	if cfg.MaxKeyLength < minMaxKeyLength {
+		return cfg, fmt.Errorf("REBALANCE_MAX_KEY_LENGTH must be at least %d, got %d", minMaxKeyLength, cfg.MaxKeyLength)
+	}
+
+	return cfg, nil
+}
+
+type Rebalancer struct {
+	cfg Config
+}
+
+func New(cfg Config) *Rebalancer {
+	return &Rebalancer{cfg: cfg}
+}
+
+// Run rebalances once right away and then on every interval until ctx is
+// cancelled. A user that has started is always finished.
+func (rb *Rebalancer) Run(ctx context.Context) {
+	ticker := time.NewTicker(rb.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		users, err := rb.RebalanceOnce(ctx)
+		if err != nil {
+			logrus.WithError(err).Error("failed to rebalance todo positions")
+		}
+		if users > 0 {
+			logrus.WithField("users", users).Info("rebalanced todo positions")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RebalanceOnce gives fresh positions to the todos of every user whose keys
+// grew too long or collide, and reports how many users were rebalanced. Users
+// are walked in id order and each is handled at most once, so that a list
+// that still qualifies after a rebalance cannot keep the pass going.
+func (rb *Rebalancer) RebalanceOnce(ctx context.Context) (int, error) {
+	var total int
+	var after string
+	for ctx.Err() == nil {
+		userIDs, err := dbHelper.GetUsersToRebalance(rb.cfg.MaxKeyLength, after, rb.cfg.BatchSize)
+		if err != nil || len(userIDs) == 0 {
+			return total, err
+		}
+		after = userIDs[len(userIDs)-1]
+
+		for _, userID := range userIDs {
+			if ctx.Err() != nil {
+				return total, nil
+			}
+			txErr := database.Tx(func(tx *sqlx.Tx) error {
+				return dbHelper.RebalanceTodoPositions(tx, userID)
+			})
+			if txErr != nil {
+				return total, fmt.Errorf("rebalancing user %s: %w", userID, txErr)
+			}
+			total++
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,51 @@
+package rebalancer
+
+import (
+	"Todo/position"
+	"testing"
+)
+
+func TestConfigFromEnvMaxKeyLength(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		invalid bool
+	}{
+		{value: "", want: position.MaxKeyLength},
+		{value: "4", want: 4},
+		{value: "32", want: 32},
+		{value: "3", invalid: true},
+		{value: "0", invalid: true},
+		{value: "-1", invalid: true},
+		{value: "long", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("REBALANCE_MAX_KEY_LENGTH", tt.value)
+		cfg, err := ConfigFromEnv()
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("REBALANCE_MAX_KEY_LENGTH=%q: expected an error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("REBALANCE_MAX_KEY_LENGTH=%q: unexpected error %v", tt.value, err)
+			continue
+		}
+		if cfg.MaxKeyLength != tt.want {
+			t.Errorf("REBALANCE_MAX_KEY_LENGTH=%q: got %d, want %d", tt.value, cfg.MaxKeyLength, tt.want)
+		}
+	}
+}
+
+// TestMinMaxKeyLength checks that a rebalanced list of any size the minimum
+// is meant to cover fits under it.
+func TestMinMaxKeyLength(t *testing.T) {
+	for _, n := range []int{1, 1000, 250000} {
+		keys := position.Spread(n)
+		if len(keys[len(keys)-1]) > minMaxKeyLength {
+			t.Errorf("Spread(%d) returned %q, longer than %d digits", n, keys[len(keys)-1], minMaxKeyLength)
+		}
+	}
+}
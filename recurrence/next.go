@@ -0,0 +1,341 @@
+package recurrence
+
+import (
+	"sort"
+	"time"
+)
+
+// searchYears bounds the search for the next occurrence so that rules which
+// can never match again (e.g. BYMONTHDAY=31;BYMONTH=2) terminate. It spans
+// time rather than periods so that sparse rules of short periods, like a
+// daily rule limited to leap days, still find their next occurrence; a
+// century covers even a leap day falling on a given weekday.
+const searchYears = 100
+
+// date is a calendar day without a clock or location.
+type date struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func dateOf(t time.Time) date {
+	y, m, d := t.Date()
+	return date{y, m, d}
+}
+
+// toTime uses UTC purely as a calendar; it is never treated as an instant.
+func (d date) toTime() time.Time {
+	return time.Date(d.year, d.month, d.day, 0, 0, 0, 0, time.UTC)
+}
+
+func (d date) addDays(n int) date {
+	return dateOf(d.toTime().AddDate(0, 0, n))
+}
+
+func (d date) weekday() time.Weekday {
+	return d.toTime().Weekday()
+}
+
+func (d date) before(other date) bool {
+	return d.toTime().Before(other.toTime())
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func daysInYear(year int) int {
+	return time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+// Next returns the first occurrence of the series anchored at start that falls
+// strictly after the given instant. Occurrences keep the wall clock time of
+// start in loc across DST changes: a time skipped by a forward transition is
+// shifted by the length of the gap, and an ambiguous time resolves to its
+// first occurrence, as RFC 5545 prescribes. The boolean is false once the
+// series has ended. COUNT is not applied here since it depends on how many
+// occurrences were already produced.
+func (r Rule) Next(start, after time.Time, loc *time.Location) (time.Time, bool) {
+	local := start.In(loc)
+	anchor := dateOf(local)
+	hour, minute, second := local.Clock()
+
+	until := r.Until
+	if r.untilIsLocal && !until.IsZero() {
+		until = wallClock(dateOf(until), until.Hour(), until.Minute(), until.Second(), until.Nanosecond(), loc)
+	}
+
+	first := 0
+	horizon := anchor
+	if after.After(start) {
+		horizon = dateOf(after.In(loc))
+		first = r.periodsBetween(anchor, horizon)
+		// the occurrence may lie in an earlier period when the local day of
+		// after differs from the period the instant belongs to
+		if first > 0 {
+			first--
+		}
+	}
+	horizon = dateOf(horizon.toTime().AddDate(searchYears, 0, 0))
+
+	for p := first; !horizon.before(r.periodStart(anchor, p)); p++ {
+		for _, day := range r.candidates(anchor, p) {
+			if day.before(anchor) {
+				continue
+			}
+			occurrence := wallClock(day, hour, minute, second, local.Nanosecond(), loc)
+			if !occurrence.After(after) {
+				continue
+			}
+			if !until.IsZero() && occurrence.After(until) {
+				return time.Time{}, false
+			}
+			return occurrence, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// periodsBetween returns how many whole intervals lie between the periods of
+// from and to.
+func (r Rule) periodsBetween(from, to date) int {
+	var units int
+	switch r.Freq {
+	case Daily:
+		units = int(to.toTime().Sub(from.toTime()).Hours() / 24)
+	case Weekly:
+		days := int(r.weekStartOf(to).toTime().Sub(r.weekStartOf(from).toTime()).Hours() / 24)
+		units = days / 7
+	case Monthly:
+		units = (to.year-from.year)*12 + int(to.month) - int(from.month)
+	case Yearly:
+		units = to.year - from.year
+	}
+	if units < 0 {
+		return 0
+	}
+	return units / r.Interval
+}
+
+// periodStart returns the first day of the p-th period of the series.
+func (r Rule) periodStart(anchor date, p int) date {
+	switch r.Freq {
+	case Weekly:
+		return r.weekStartOf(anchor).addDays(p * r.Interval * 7)
+	case Monthly:
+		return dateOf(time.Date(anchor.year, anchor.month+time.Month(p*r.Interval), 1, 0, 0, 0, 0, time.UTC))
+	case Yearly:
+		return date{anchor.year + p*r.Interval, time.January, 1}
+	default:
+		return anchor.addDays(p * r.Interval)
+	}
+}
+
+func (r Rule) weekStartOf(d date) date {
+	offset := (int(d.weekday()) - int(r.WeekStart) + 7) % 7
+	return d.addDays(-offset)
+}
+
+// candidates lists the days of the p-th period of the series, sorted and
+// already limited by the BY* parts of the rule.
+func (r Rule) candidates(anchor date, p int) []date {
+	var days []date
+
+	switch r.Freq {
+	case Daily:
+		day := anchor.addDays(p * r.Interval)
+		if r.monthMatches(day) && r.monthDayMatches(day) && r.weekdayMatches(day) {
+			days = append(days, day)
+		}
+
+	case Weekly:
+		weekStart := r.weekStartOf(anchor).addDays(p * r.Interval * 7)
+		for i := 0; i < 7; i++ {
+			day := weekStart.addDays(i)
+			if len(r.ByDay) == 0 && day.weekday() != anchor.weekday() {
+				continue
+			}
+			if r.weekdayMatches(day) && r.monthMatches(day) {
+				days = append(days, day)
+			}
+		}
+
+	case Monthly:
+		month := time.Date(anchor.year, anchor.month+time.Month(p*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		if r.monthMatches(dateOf(month)) {
+			days = r.daysOfMonth(month.Year(), month.Month(), anchor.day)
+		}
+
+	case Yearly:
+		year := anchor.year + p*r.Interval
+		switch {
+		case len(r.ByDay) > 0 && len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0:
+			days = r.weekdaysOfYear(year)
+		default:
+			months := r.ByMonth
+			if len(months) == 0 {
+				months = []time.Month{anchor.month}
+			}
+			for _, month := range months {
+				days = append(days, r.daysOfMonth(year, month, anchor.day)...)
+			}
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].before(days[j]) })
+	return dedupe(days)
+}
+
+// daysOfMonth expands BYMONTHDAY and BYDAY within a single month, falling back
+// to the anchor's day of month. Days that do not exist in the month, like the
+// 31st of April, are skipped rather than moved.
+func (r Rule) daysOfMonth(year int, month time.Month, anchorDay int) []date {
+	last := daysIn(year, month)
+	var days []date
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, n := range r.ByMonthDay {
+			day := n
+			if n < 0 {
+				day = last + n + 1
+			}
+			if day < 1 || day > last {
+				continue
+			}
+			d := date{year, month, day}
+			if r.weekdayMatches(d) {
+				days = append(days, d)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			var matches []date
+			for day := 1; day <= last; day++ {
+				if d := (date{year, month, day}); d.weekday() == wd.Weekday {
+					matches = append(matches, d)
+				}
+			}
+			days = append(days, pick(matches, wd.N)...)
+		}
+	default:
+		if anchorDay <= last {
+			days = append(days, date{year, month, anchorDay})
+		}
+	}
+	return days
+}
+
+// weekdaysOfYear expands BYDAY over a whole year, where numbered entries such
+// as 20MO count weeks from the start (or, when negative, the end) of the year.
+func (r Rule) weekdaysOfYear(year int) []date {
+	jan1 := date{year, time.January, 1}
+	total := daysInYear(year)
+	var days []date
+	for _, wd := range r.ByDay {
+		var matches []date
+		for i := 0; i < total; i++ {
+			if d := jan1.addDays(i); d.weekday() == wd.Weekday {
+				matches = append(matches, d)
+			}
+		}
+		days = append(days, pick(matches, wd.N)...)
+	}
+	return days
+}
+
+// pick returns the n-th match (negative counts from the end) or all of them
+// when n is zero.
+func pick(matches []date, n int) []date {
+	switch {
+	case n == 0:
+		return matches
+	case n > 0 && n <= len(matches):
+		return matches[n-1 : n]
+	case n < 0 && -n <= len(matches):
+		return matches[len(matches)+n : len(matches)+n+1]
+	}
+	return nil
+}
+
+func (r Rule) monthMatches(d date) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, month := range r.ByMonth {
+		if month == d.month {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) monthDayMatches(d date) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := daysIn(d.year, d.month)
+	for _, n := range r.ByMonthDay {
+		if n == d.day || n < 0 && last+n+1 == d.day {
+			return true
+		}
+	}
+	return false
+}
+
+// weekdayMatches applies BYDAY as a filter, ignoring ordinals which only make
+// sense when expanding.
+func (r Rule) weekdayMatches(d date) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == d.weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(days []date) []date {
+	out := days[:0]
+	for i, day := range days {
+		if i == 0 || day != days[i-1] {
+			out = append(out, day)
+		}
+	}
+	return out
+}
+
+// wallClock returns the instant at which clocks in loc show the given day and
+// time. Unlike time.Date it pins down the behaviour around DST transitions:
+// a skipped time is read with the offset in effect before the gap, and an
+// ambiguous time resolves to the earlier of its two instants.
+func wallClock(d date, hour, minute, second, nanosecond int, loc *time.Location) time.Time {
+	naive := time.Date(d.year, d.month, d.day, hour, minute, second, nanosecond, time.UTC)
+
+	offsetAt := func(t time.Time) time.Duration {
+		_, offset := t.In(loc).Zone()
+		return time.Duration(offset) * time.Second
+	}
+	before := offsetAt(naive.Add(-24 * time.Hour))
+	after := offsetAt(naive.Add(24 * time.Hour))
+
+	var valid []time.Time
+	for _, offset := range []time.Duration{before, after} {
+		instant := naive.Add(-offset)
+		if offsetAt(instant) == offset {
+			valid = append(valid, instant)
+		}
+	}
+
+	switch {
+	case len(valid) == 0:
+		return naive.Add(-before).In(loc)
+	case len(valid) == 2 && valid[1].Before(valid[0]):
+		return valid[1].In(loc)
+	default:
+		return valid[0].In(loc)
+	}
+}
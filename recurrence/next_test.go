@@ -0,0 +1,238 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestNext(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	at := func(layout string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", layout, newYork)
+		if err != nil {
+			t.Fatalf("parse %q: %v", layout, err)
+		}
+		return parsed
+	}
+	utc := func(layout string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", layout)
+		if err != nil {
+			t.Fatalf("parse %q: %v", layout, err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		after time.Time
+		want  time.Time
+		ended bool
+	}{
+		{
+			name:  "spring forward gap shifts by the gap",
+			rule:  "FREQ=DAILY",
+			start: at("2024-03-09 02:30"),
+			after: at("2024-03-09 02:30"),
+			want:  utc("2024-03-10 07:30"), // 03:30 EDT
+		},
+		{
+			name:  "day after the gap keeps the wall clock",
+			rule:  "FREQ=DAILY",
+			start: at("2024-03-09 02:30"),
+			after: utc("2024-03-10 07:30"),
+			want:  utc("2024-03-11 06:30"), // 02:30 EDT
+		},
+		{
+			name:  "fall back ambiguity picks the first instant",
+			rule:  "FREQ=DAILY",
+			start: at("2024-11-02 01:30"),
+			after: at("2024-11-02 01:30"),
+			want:  utc("2024-11-03 05:30"), // 01:30 EDT
+		},
+		{
+			name:  "fall back does not repeat the ambiguous time",
+			rule:  "FREQ=DAILY",
+			start: at("2024-11-02 01:30"),
+			after: utc("2024-11-03 05:30"),
+			want:  utc("2024-11-04 06:30"), // 01:30 EST
+		},
+		{
+			name:  "weekly across spring forward",
+			rule:  "FREQ=WEEKLY",
+			start: at("2024-03-04 09:00"),
+			after: at("2024-03-04 09:00"),
+			want:  utc("2024-03-11 13:00"), // 09:00 EDT
+		},
+		{
+			name:  "last friday of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR",
+			start: at("2024-01-26 09:00"),
+			after: at("2024-01-26 09:00"),
+			want:  at("2024-02-23 09:00"),
+		},
+		{
+			name:  "second to last monday of the year",
+			rule:  "FREQ=YEARLY;BYDAY=-2MO",
+			start: at("2024-12-23 09:00"),
+			after: at("2024-12-23 09:00"),
+			want:  at("2025-12-22 09:00"),
+		},
+		{
+			name:  "last day of the month into a leap february",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: at("2024-01-31 09:00"),
+			after: at("2024-01-31 09:00"),
+			want:  at("2024-02-29 09:00"),
+		},
+		{
+			name:  "last day of the month after february",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: at("2024-01-31 09:00"),
+			after: at("2024-02-29 09:00"),
+			want:  at("2024-03-31 09:00"),
+		},
+		{
+			name:  "second to last day of a common february",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-2",
+			start: at("2025-01-30 09:00"),
+			after: at("2025-01-30 09:00"),
+			want:  at("2025-02-27 09:00"),
+		},
+		{
+			name:  "month end skips months that are too short",
+			rule:  "FREQ=MONTHLY",
+			start: at("2024-01-31 09:00"),
+			after: at("2024-01-31 09:00"),
+			want:  at("2024-03-31 09:00"),
+		},
+		{
+			name:  "yearly leap day waits for the next leap year",
+			rule:  "FREQ=YEARLY",
+			start: at("2024-02-29 09:00"),
+			after: at("2024-02-29 09:00"),
+			want:  at("2028-02-29 09:00"),
+		},
+		{
+			name:  "daily rule limited to leap days",
+			rule:  "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29",
+			start: at("2024-02-29 09:00"),
+			after: at("2024-02-29 09:00"),
+			want:  at("2028-02-29 09:00"),
+		},
+		{
+			name:  "leap day on a monday",
+			rule:  "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29;BYDAY=MO",
+			start: at("2016-02-29 09:00"),
+			after: at("2016-02-29 09:00"),
+			want:  at("2044-02-29 09:00"),
+		},
+		{
+			name:  "rule that never matches ends the series",
+			rule:  "FREQ=MONTHLY;BYMONTH=2;BYMONTHDAY=31",
+			start: at("2024-01-31 09:00"),
+			after: at("2024-01-31 09:00"),
+			ended: true,
+		},
+		{
+			name:  "occurrence before UNTIL",
+			rule:  "FREQ=DAILY;UNTIL=20240105T000000Z",
+			start: utc("2024-01-03 09:00"),
+			after: utc("2024-01-03 09:00"),
+			want:  utc("2024-01-04 09:00"),
+		},
+		{
+			name:  "occurrence past UNTIL ends the series",
+			rule:  "FREQ=DAILY;UNTIL=20240105T000000Z",
+			start: utc("2024-01-03 09:00"),
+			after: utc("2024-01-04 09:00"),
+			ended: true,
+		},
+		{
+			name:  "date only UNTIL includes the whole local day",
+			rule:  "FREQ=DAILY;UNTIL=20240105",
+			start: at("2024-01-03 21:00"),
+			after: at("2024-01-04 21:00"),
+			want:  at("2024-01-05 21:00"),
+		},
+		{
+			name:  "local UNTIL is read in the rule's timezone",
+			rule:  "FREQ=DAILY;UNTIL=20240105T200000",
+			start: at("2024-01-03 21:00"),
+			after: at("2024-01-04 21:00"),
+			ended: true,
+		},
+		{
+			name:  "COUNT is left to the caller",
+			rule:  "FREQ=DAILY;COUNT=1",
+			start: at("2024-01-03 09:00"),
+			after: at("2024-01-03 09:00"),
+			want:  at("2024-01-04 09:00"),
+		},
+		{
+			name:  "after far past the start",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+			start: at("2024-01-02 09:00"),
+			after: at("2024-06-05 09:00"),
+			want:  at("2024-06-06 09:00"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.rule, err)
+			}
+
+			got, ok := rule.Next(tt.start, tt.after, newYork)
+			if tt.ended {
+				if ok {
+					t.Fatalf("expected the series to end, got %s", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("expected %s, the series ended", tt.want)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("got %s, want %s", got, tt.want.In(newYork))
+			}
+		})
+	}
+}
+
+func TestExhausted(t *testing.T) {
+	tests := []struct {
+		rule     string
+		produced int
+		want     bool
+	}{
+		{"FREQ=DAILY;COUNT=3", 1, false},
+		{"FREQ=DAILY;COUNT=3", 2, false},
+		{"FREQ=DAILY;COUNT=3", 3, true},
+		{"FREQ=DAILY;COUNT=3", 4, true},
+		{"FREQ=DAILY", 1000, false},
+		{"FREQ=DAILY;UNTIL=20240105T000000Z", 1000, false},
+	}
+
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.rule, err)
+		}
+		if got := rule.Exhausted(tt.produced); got != tt.want {
+			t.Errorf("%s after %d occurrences: got %v, want %v", tt.rule, tt.produced, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	// timezones must resolve even on hosts without a zoneinfo database
+	_ "time/tzdata"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+var ErrUnsupported = errors.New("unsupported recurrence rule")
+
+// WeekdayNum is a BYDAY entry such as MO, 2TU or -1FR. N is zero when the rule
+// applies to every such weekday of the period.
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule is the subset of an RFC 5545 RRULE that todos can recur by: FREQ
+// DAILY to YEARLY with INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH and
+// WKST.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+
+	// untilIsLocal is set when UNTIL carried no UTC designator, in which case
+	// its wall clock is read in the timezone the rule is evaluated in.
+	untilIsLocal bool
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Normalize strips the optional "RRULE:" prefix and surrounding whitespace and
+// upper-cases the rule, which is the form rules are stored in.
+func Normalize(value string) string {
+	return strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "RRULE:")
+}
+
+// Parse parses an RRULE value, with or without its "RRULE:" prefix.
+func Parse(value string) (Rule, error) {
+	rule := Rule{Interval: 1, WeekStart: time.Monday}
+
+	value = Normalize(value)
+	if value == "" {
+		return rule, errors.New("empty recurrence rule")
+	}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ";") {
+		name, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return rule, fmt.Errorf("malformed rule part %q", part)
+		}
+		if seen[name] {
+			return rule, fmt.Errorf("duplicate rule part %s", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch Frequency(val) {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = Frequency(val)
+			default:
+				err = fmt.Errorf("%w: FREQ=%s", ErrUnsupported, val)
+			}
+		case "INTERVAL":
+			rule.Interval, err = parsePositive(val)
+		case "COUNT":
+			rule.Count, err = parsePositive(val)
+		case "UNTIL":
+			rule.Until, rule.untilIsLocal, err = parseUntil(val)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(val)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseIntList(val, 1, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseIntList(val, 1, 12)
+			for _, month := range months {
+				if month < 0 {
+					err = fmt.Errorf("invalid BYMONTH value %d", month)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(month))
+			}
+		case "WKST":
+			day, ok := weekdays[val]
+			if !ok {
+				err = fmt.Errorf("invalid WKST value %q", val)
+			}
+			rule.WeekStart = day
+		default:
+			err = fmt.Errorf("%w: %s", ErrUnsupported, name)
+		}
+		if err != nil {
+			return rule, err
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return rule, errors.New("COUNT and UNTIL are mutually exclusive")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly && rule.Freq != Yearly {
+			return rule, fmt.Errorf("numbered BYDAY is only valid for MONTHLY and YEARLY rules")
+		}
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq == Weekly {
+		return rule, errors.New("BYMONTHDAY is not valid for WEEKLY rules")
+	}
+	return rule, nil
+}
+
+// Exhausted reports whether a series that has produced the given number of
+// occurrences has reached its COUNT. Series without COUNT never are.
+func (r Rule) Exhausted(produced int) bool {
+	return r.Count > 0 && produced >= r.Count
+}
+
+func parsePositive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("expected a positive integer, got %q", value)
+	}
+	return n, nil
+}
+
+// parseIntList parses a comma separated list of values whose magnitude lies in
+// [min, max]; negative values count from the end of the period.
+func parseIntList(value string, min, max int) ([]int, error) {
+	fields := strings.Split(value, ",")
+	values := make([]int, 0, len(fields))
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", field)
+		}
+		if magnitude := abs(n); magnitude < min || magnitude > max {
+			return nil, fmt.Errorf("value %d out of range", n)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	fields := strings.Split(value, ",")
+	days := make([]WeekdayNum, 0, len(fields))
+	for _, field := range fields {
+		if len(field) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY value %q", field)
+		}
+		day, ok := weekdays[field[len(field)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY value %q", field)
+		}
+		entry := WeekdayNum{Weekday: day}
+		if prefix := field[:len(field)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || abs(n) > 53 {
+				return nil, fmt.Errorf("invalid BYDAY value %q", field)
+			}
+			entry.N = n
+		}
+		days = append(days, entry)
+	}
+	return days, nil
+}
+
+func parseUntil(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse("20060102T150405", value); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		// a date-only UNTIL includes the whole of that day
+		return t.Add(24*time.Hour - time.Nanosecond), true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid UNTIL value %q", value)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
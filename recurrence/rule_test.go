@@ -0,0 +1,124 @@
+package recurrence
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Rule
+	}{
+		{
+			value: "FREQ=DAILY",
+			want:  Rule{Freq: Daily, Interval: 1, WeekStart: time.Monday},
+		},
+		{
+			value: " rrule:freq=weekly;interval=2;byday=mo,fr;wkst=su ",
+			want: Rule{
+				Freq:      Weekly,
+				Interval:  2,
+				ByDay:     []WeekdayNum{{Weekday: time.Monday}, {Weekday: time.Friday}},
+				WeekStart: time.Sunday,
+			},
+		},
+		{
+			value: "FREQ=MONTHLY;BYDAY=-1FR,2TU;COUNT=5",
+			want: Rule{
+				Freq:      Monthly,
+				Interval:  1,
+				Count:     5,
+				ByDay:     []WeekdayNum{{N: -1, Weekday: time.Friday}, {N: 2, Weekday: time.Tuesday}},
+				WeekStart: time.Monday,
+			},
+		},
+		{
+			value: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1,29",
+			want: Rule{
+				Freq:       Yearly,
+				Interval:   1,
+				ByMonthDay: []int{-1, 29},
+				ByMonth:    []time.Month{time.February},
+				WeekStart:  time.Monday,
+			},
+		},
+		{
+			value: "FREQ=DAILY;UNTIL=20240105T093000Z",
+			want: Rule{
+				Freq:      Daily,
+				Interval:  1,
+				Until:     time.Date(2024, time.January, 5, 9, 30, 0, 0, time.UTC),
+				WeekStart: time.Monday,
+			},
+		},
+		{
+			value: "FREQ=DAILY;UNTIL=20240105",
+			want: Rule{
+				Freq:         Daily,
+				Interval:     1,
+				Until:        time.Date(2024, time.January, 5, 23, 59, 59, 999999999, time.UTC),
+				WeekStart:    time.Monday,
+				untilIsLocal: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.value)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		value       string
+		unsupported bool
+	}{
+		{value: ""},
+		{value: "RRULE:"},
+		{value: "INTERVAL=2"},
+		{value: "FREQ"},
+		{value: "FREQ="},
+		{value: "FREQ=DAILY;;"},
+		{value: "FREQ=HOURLY", unsupported: true},
+		{value: "FREQ=DAILY;BYHOUR=9", unsupported: true},
+		{value: "FREQ=DAILY;FREQ=WEEKLY"},
+		{value: "FREQ=DAILY;INTERVAL=0"},
+		{value: "FREQ=DAILY;COUNT=-1"},
+		{value: "FREQ=DAILY;COUNT=two"},
+		{value: "FREQ=DAILY;COUNT=2;UNTIL=20240105T000000Z"},
+		{value: "FREQ=DAILY;UNTIL=2024-01-05"},
+		{value: "FREQ=MONTHLY;BYMONTHDAY=0"},
+		{value: "FREQ=MONTHLY;BYMONTHDAY=32"},
+		{value: "FREQ=MONTHLY;BYMONTHDAY=-32"},
+		{value: "FREQ=YEARLY;BYMONTH=13"},
+		{value: "FREQ=YEARLY;BYMONTH=-1"},
+		{value: "FREQ=WEEKLY;BYDAY=XX"},
+		{value: "FREQ=MONTHLY;BYDAY=0MO"},
+		{value: "FREQ=YEARLY;BYDAY=54MO"},
+		{value: "FREQ=WEEKLY;BYDAY=1MO"},
+		{value: "FREQ=DAILY;BYDAY=-1FR"},
+		{value: "FREQ=WEEKLY;BYMONTHDAY=1"},
+		{value: "FREQ=WEEKLY;WKST=XX"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.value)
+		if err == nil {
+			t.Errorf("%q: expected an error", tt.value)
+			continue
+		}
+		if got := errors.Is(err, ErrUnsupported); got != tt.unsupported {
+			t.Errorf("%q: unsupported %v, want %v (%v)", tt.value, got, tt.unsupported, err)
+		}
+	}
+}
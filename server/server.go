@@ -31,6 +31,7 @@ func SetupRoutes() *Server {
 
 		v1.Group(func(r chi.Router) {
 			r.Use(middlewares.Authenticate)
+			r.Use(middlewares.SelectWorkspace)
 
 			r.Route("/user", func(user chi.Router) {
 				user.Get("/profile", handlers.GetUser)
@@ -42,10 +43,135 @@ func SetupRoutes() *Server {
 				todo.Post("/", handlers.CreateTodo)
 				todo.Get("/", handlers.GetAllTodos)
 				todo.Delete("/delete-all", handlers.DeleteAllTodos)
+				todo.Get("/trash", handlers.GetTrashedTodos)
+				todo.Get("/assigned", handlers.GetAssignedTodos)
+				todo.Post("/bulk", handlers.BulkTodos)
 
 				todo.Route("/{todoId}", func(todoIDRoute chi.Router) {
+					todoIDRoute.Patch("/", handlers.UpdateTodo)
 					todoIDRoute.Delete("/", handlers.DeleteTodo)
 					todoIDRoute.Put("/mark-completed", handlers.MarkCompleted)
+					todoIDRoute.Put("/mark-incomplete", handlers.MarkIncomplete)
+					todoIDRoute.Put("/move", handlers.MoveTodo)
+					todoIDRoute.Put("/status", handlers.TransitionTodo)
+					todoIDRoute.Put("/assignee", handlers.AssignTodo)
+					todoIDRoute.Post("/restore", handlers.RestoreTodo)
+					todoIDRoute.Delete("/permanent", handlers.PurgeTodo)
+					todoIDRoute.Get("/history", handlers.GetTodoHistory)
+
+					todoIDRoute.Route("/subtasks", func(subtasks chi.Router) {
+						subtasks.Post("/", handlers.CreateSubtask)
+						subtasks.Get("/", handlers.GetSubtasks)
+					})
+
+					todoIDRoute.Route("/tags", func(tags chi.Router) {
+						tags.Post("/", handlers.AttachTags)
+						tags.Delete("/{tagId}", handlers.DetachTag)
+					})
+
+					todoIDRoute.Route("/blockers", func(blockers chi.Router) {
+						blockers.Post("/", handlers.AddTodoBlockers)
+						blockers.Delete("/{blockerId}", handlers.RemoveTodoBlocker)
+					})
+
+					todoIDRoute.Route("/comments", func(comments chi.Router) {
+						comments.Post("/", handlers.CreateComment)
+						comments.Get("/", handlers.GetComments)
+						comments.Put("/{commentId}", handlers.UpdateComment)
+						comments.Delete("/{commentId}", handlers.DeleteComment)
+					})
+
+					todoIDRoute.Route("/shares", func(shares chi.Router) {
+						shares.Post("/", handlers.ShareTodo)
+						shares.Get("/", handlers.GetTodoShares)
+						shares.Put("/{shareId}", handlers.UpdateTodoShare)
+						shares.Delete("/{shareId}", handlers.DeleteTodoShare)
+					})
+
+					todoIDRoute.Route("/attachments", func(attachments chi.Router) {
+						attachments.Post("/", handlers.UploadAttachment)
+						attachments.Get("/", handlers.GetAttachments)
+						attachments.Get("/{attachmentId}", handlers.DownloadAttachment)
+						attachments.Delete("/{attachmentId}", handlers.DeleteAttachment)
+					})
+				})
+			})
+
+			r.Route("/project", func(project chi.Router) {
+				project.Post("/", handlers.CreateProject)
+				project.Get("/", handlers.GetAllProjects)
+
+				project.Route("/{projectId}", func(projectIDRoute chi.Router) {
+					projectIDRoute.Put("/", handlers.RenameProject)
+					projectIDRoute.Delete("/", handlers.DeleteProject)
+
+					projectIDRoute.Route("/shares", func(shares chi.Router) {
+						shares.Post("/", handlers.ShareProject)
+						shares.Get("/", handlers.GetProjectShares)
+						shares.Put("/{shareId}", handlers.UpdateProjectShare)
+						shares.Delete("/{shareId}", handlers.DeleteProjectShare)
+					})
+				})
+			})
+
+			r.Route("/workspace", func(workspace chi.Router) {
+				workspace.Post("/", handlers.CreateWorkspace)
+				workspace.Get("/", handlers.GetWorkspaces)
+				workspace.Post("/invitations/accept", handlers.AcceptInvitation)
+
+				workspace.Route("/{workspaceId}", func(workspaceIDRoute chi.Router) {
+					workspaceIDRoute.Get("/", handlers.GetWorkspace)
+					workspaceIDRoute.Put("/", handlers.RenameWorkspace)
+					workspaceIDRoute.Delete("/", handlers.DeleteWorkspace)
+
+					workspaceIDRoute.Route("/members", func(members chi.Router) {
+						members.Get("/", handlers.GetWorkspaceMembers)
+						members.Put("/{userId}", handlers.UpdateWorkspaceMember)
+						members.Delete("/{userId}", handlers.RemoveWorkspaceMember)
+					})
+
+					workspaceIDRoute.Route("/invitations", func(invitations chi.Router) {
+						invitations.Post("/", handlers.InviteToWorkspace)
+						invitations.Get("/", handlers.GetInvitations)
+						invitations.Delete("/{invitationId}", handlers.RevokeInvitation)
+					})
+				})
+			})
+
+			r.Get("/activity", handlers.GetActivity)
+
+			r.Route("/filter", func(filter chi.Router) {
+				filter.Post("/", handlers.CreateSavedFilter)
+				filter.Get("/", handlers.GetAllSavedFilters)
+
+				filter.Route("/{filterId}", func(filterIDRoute chi.Router) {
+					filterIDRoute.Get("/", handlers.GetSavedFilter)
+					filterIDRoute.Put("/", handlers.UpdateSavedFilter)
+					filterIDRoute.Delete("/", handlers.DeleteSavedFilter)
+					filterIDRoute.Get("/todos", handlers.GetSavedFilterTodos)
+				})
+			})
+
+			r.Get("/board", handlers.GetBoard)
+
+			r.Route("/status", func(status chi.Router) {
+				status.Post("/", handlers.CreateStatus)
+				status.Get("/", handlers.GetStatuses)
+				status.Put("/order", handlers.ReorderStatuses)
+
+				status.Route("/{statusId}", func(statusIDRoute chi.Router) {
+					statusIDRoute.Put("/", handlers.UpdateStatus)
+					statusIDRoute.Delete("/", handlers.DeleteStatus)
+				})
+			})
+
+			r.Route("/tag", func(tag chi.Router) {
+				tag.Post("/", handlers.CreateTag)
+				tag.Get("/", handlers.GetAllTags)
+
+				tag.Route("/{tagId}", func(tagIDRoute chi.Router) {
+					tagIDRoute.Put("/", handlers.RenameTag)
+					tagIDRoute.Delete("/", handlers.DeleteTag)
 				})
 			})
 		})
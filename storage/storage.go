@@ -0,0 +1,147 @@
+// Package storage keeps the contents of uploaded files outside of Postgres.
+// The database only records the key under which a blob was stored, so the
+// backend can be swapped through configuration.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores opaque blobs under keys made of slash separated segments.
+// Deleting a key that does not exist is not an error.
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	Blobs  BlobStore
+	Limits UploadLimits
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+const (
+	defaultDir     = "attachments"
+	defaultRegion  = "us-east-1"
+	defaultMaxSize = 10 << 20
+)
+
+var defaultAllowedTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp",
+	"application/pdf", "application/zip", "text/plain",
+}
+
+// UploadLimits caps the size of an upload and lists the media types that are
+// accepted, without parameters such as charset.
+type UploadLimits struct {
+	MaxSize      int64
+	AllowedTypes []string
+}
+
+// Allows reports whether a content type such as "text/plain; charset=utf-8"
+// is one of the allowed media types.
+func (l UploadLimits) Allows(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range l.AllowedTypes {
+		if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+type Config struct {
+	Backend string
+	Dir     string
+	S3      S3Config
+	Limits  UploadLimits
+}
+
+// ConfigFromEnv reads STORAGE_BACKEND ("local" or "s3"), STORAGE_DIR for the
+// local backend, the S3_* variables for the s3 backend, ATTACHMENT_MAX_SIZE
+// in bytes and ATTACHMENT_ALLOWED_TYPES as a comma separated list, falling
+// back to defaults when unset.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Backend: BackendLocal,
+		Dir:     defaultDir,
+		S3: S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          defaultRegion,
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		},
+		Limits: UploadLimits{
+			MaxSize:      defaultMaxSize,
+			AllowedTypes: defaultAllowedTypes,
+		},
+	}
+
+	for env, target := range map[string]*string{
+		"STORAGE_BACKEND": &cfg.Backend,
+		"STORAGE_DIR":     &cfg.Dir,
+		"S3_REGION":       &cfg.S3.Region,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*target = value
+		}
+	}
+
+	if value := os.Getenv("ATTACHMENT_MAX_SIZE"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("ATTACHMENT_MAX_SIZE must be a positive integer, got %q", value)
+		}
+		cfg.Limits.MaxSize = n
+	}
+
+	if value := os.Getenv("ATTACHMENT_ALLOWED_TYPES"); value != "" {
+		cfg.Limits.AllowedTypes = nil
+		for _, allowed := range strings.Split(value, ",") {
+			if allowed = strings.TrimSpace(allowed); allowed != "" {
+				cfg.Limits.AllowedTypes = append(cfg.Limits.AllowedTypes, allowed)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// Setup opens the configured backend and makes it available as Blobs.
+func Setup(cfg Config) error {
+	var store BlobStore
+	var err error
+	switch cfg.Backend {
+	case BackendLocal:
+		store, err = NewLocalStore(cfg.Dir)
+	case BackendS3:
+		store, err = NewS3Store(cfg.S3)
+	default:
+		err = fmt.Errorf("unsupported storage backend %q", cfg.Backend)
+	}
+	if err != nil {
+		return err
+	}
+
+	Blobs = store
+	Limits = cfg.Limits
+	return nil
+}
@@ -3,6 +3,9 @@ package utils
 import (
 	"Todo/models"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
@@ -12,12 +15,15 @@ import (
 	"math/big"
 	"net/http"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 )
 
 var generator *shortid.Shortid
 
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
 const generatorSeed = 1000
 
 func init() {
@@ -59,6 +65,45 @@ func ParseBody(body io.Reader, out interface{}) error {
 	return json.NewDecoder(body).Decode(out)
 }
 
+// ParseMergePatch decodes a JSON Merge Patch (RFC 7396) document into out and
+// returns the members that were explicitly set to null, since those are
+// indistinguishable from absent members once decoded into pointer fields.
+func ParseMergePatch(body io.Reader, out interface{}) (map[string]bool, error) {
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	nulls := make(map[string]bool)
+	for key, value := range raw {
+		if string(value) == "null" {
+			nulls[key] = true
+		}
+	}
+
+	patch, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	return nulls, json.Unmarshal(patch, out)
+}
+
+func EncodeCursor(cursor interface{}) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func DecodeCursor(cursor string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
 func EncodeJSONBody(resp http.ResponseWriter, data interface{}) error {
 	return json.NewEncoder(resp).Encode(data)
 }
@@ -81,6 +126,10 @@ func RespondError(w http.ResponseWriter, statusCode int, err error, messageToUse
 	}
 }
 
+func IsUUID(value string) bool {
+	return uuidPattern.MatchString(value)
+}
+
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(hashedPassword), err
@@ -90,6 +139,22 @@ func CheckPassword(password, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
 
+// GenerateToken returns a random url safe token for links sent by email.
+func GenerateToken() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// HashToken returns the digest under which a token from GenerateToken is
+// stored, so that the token itself never ends up in the database.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func GenerateJWT(userID, sessionID string) (string, error) {
 	claims := jwt.MapClaims{
 		"userId":    userID,